@@ -4,16 +4,20 @@
 package auth
 
 import (
+	"context"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 	"github.com/mitchellh/go-homedir"
 	"github.com/nimajalali/go-force/force"
 )
@@ -24,22 +28,47 @@ const (
 	salesforceOAuthEndpoint         = "/services/oauth2/token"
 )
 
+const (
+	AuthTypeJWT               = "jwt"
+	AuthTypeClientCredentials = "client_credentials"
+	AuthTypePassword          = "password"
+	AuthTypeRefreshToken      = "refresh_token"
+	AuthTypeAccessToken       = "access_token"
+	AuthTypeDevice            = "device"
+)
+
+// AuthTypes lists the supported OAuth flows
+var AuthTypes = []string{AuthTypeJWT, AuthTypeClientCredentials, AuthTypePassword, AuthTypeRefreshToken, AuthTypeAccessToken, AuthTypeDevice}
+
 type AuthResponse struct {
 	AccessToken string `json:"access_token"`
 	Scope       string `json:"scope"`
 	InstanceUrl string `json:"instance_url"`
 	Id          string `json:"id"`
 	TokenType   string `json:"token_type"`
+	// RefreshToken is only issued by flows that support it, such as the device flow
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
-func SignJWT(privateKey []byte, user string, clientId string, audience string) (string, error) {
-	priv, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
-	if err != nil {
-		return "", fmt.Errorf("not able to parse PEM: %v", err)
-	}
+const (
+	// MaxJWTExpiry is the longest lifetime Salesforce accepts, assertions expiring later are rejected
+	MaxJWTExpiry = 3 * time.Minute
+	// DefaultJWTExpiry is the lifetime of the signed assertion, the maximum so jwt_expiry can only
+	// shorten it. Salesforce only needs the assertion for the token exchange
+	DefaultJWTExpiry = MaxJWTExpiry
+	// jwtClockSkew backdates iat and nbf so the assertion is accepted when the local clock runs ahead
+	jwtClockSkew = time.Minute
+)
 
+func SignJWT(priv *rsa.PrivateKey, user string, clientId string, audience string, expiry time.Duration) (string, error) {
+	if expiry <= 0 {
+		expiry = DefaultJWTExpiry
+	}
+	now := time.Now().UTC()
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
-		ExpiresAt: time.Now().UTC().Add(3 * time.Minute).Unix(),
+		ExpiresAt: now.Add(expiry).Unix(),
+		IssuedAt:  now.Add(-jwtClockSkew).Unix(),
+		NotBefore: now.Add(-jwtClockSkew).Unix(),
 		Subject:   user,
 		Issuer:    clientId,
 		Audience:  audience,
@@ -48,18 +77,138 @@ func SignJWT(privateKey []byte, user string, clientId string, audience string) (
 	return token.SignedString(priv)
 }
 
-func Authenticate(domain string, signedJwt string) (AuthResponse, error) {
-	var oauth AuthResponse
+// JWTAudience returns the audience Salesforce expects for assertions posted to loginUrl. The
+// generic login servers are their own audience, any other host such as a My Domain or an
+// Experience Cloud site expects the login server of its environment
+func JWTAudience(loginUrl string) string {
+	u, err := url.Parse(loginUrl)
+	if err != nil || u.Hostname() == "" {
+		return loginUrl
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case "https://"+host == productionSalesforceLoginServer || "https://"+host == sandboxSalesforceLoginServer:
+		return "https://" + host
+	case isSandboxHost(host):
+		return sandboxSalesforceLoginServer
+	default:
+		return productionSalesforceLoginServer
+	}
+}
 
+// isSandboxHost detects sandbox and scratch org domains, both with enhanced domains
+// (example--dev.sandbox.my.salesforce.com) and legacy ones (example--dev.my.salesforce.com, cs42.salesforce.com)
+func isSandboxHost(host string) bool {
+	for _, suffix := range []string{".sandbox.my.salesforce.com", ".scratch.my.salesforce.com", ".sandbox.my.site.com", ".scratch.my.site.com", ".sandbox.lightning.force.com"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	if strings.HasSuffix(host, ".my.salesforce.com") && strings.Contains(strings.SplitN(host, ".", 2)[0], "--") {
+		return true
+	}
+	return legacySandboxInstance.MatchString(host)
+}
+
+var legacySandboxInstance = regexp.MustCompile(`^cs\d+\.salesforce\.com$`)
+
+func Authenticate(ctx context.Context, client *http.Client, domain string, signedJwt string) (AuthResponse, error) {
 	payload := url.Values{}
 	payload.Add("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
 	payload.Add("assertion", signedJwt)
 
+	return requestToken(ctx, client, domain, payload)
+}
+
+// AuthenticateClientCredentials performs the client credentials flow, the connected app
+// must have a run-as user configured and the domain must be the org's My Domain URL
+func AuthenticateClientCredentials(ctx context.Context, client *http.Client, domain string, clientId string, clientSecret string) (AuthResponse, error) {
+	payload := url.Values{}
+	payload.Add("grant_type", "client_credentials")
+	payload.Add("client_id", clientId)
+	payload.Add("client_secret", clientSecret)
+
+	return requestToken(ctx, client, domain, payload)
+}
+
+// AuthenticatePassword performs the username-password flow, the security token is appended
+// to the password as required when logging in from an IP address outside the trusted ranges
+func AuthenticatePassword(ctx context.Context, client *http.Client, domain string, clientId string, clientSecret string, username string, password string, securityToken string) (AuthResponse, error) {
+	payload := url.Values{}
+	payload.Add("grant_type", "password")
+	payload.Add("client_id", clientId)
+	payload.Add("client_secret", clientSecret)
+	payload.Add("username", username)
+	payload.Add("password", password+securityToken)
+
+	return requestToken(ctx, client, domain, payload)
+}
+
+// AuthenticateRefreshToken exchanges a refresh token for an access token, the client secret
+// may be empty for connected apps that don't require it, such as the Salesforce CLI
+func AuthenticateRefreshToken(ctx context.Context, client *http.Client, domain string, clientId string, clientSecret string, refreshToken string) (AuthResponse, error) {
+	payload := url.Values{}
+	payload.Add("grant_type", "refresh_token")
+	payload.Add("client_id", clientId)
+	if clientSecret != "" {
+		payload.Add("client_secret", clientSecret)
+	}
+	payload.Add("refresh_token", refreshToken)
+
+	return requestToken(ctx, client, domain, payload)
+}
+
+// SfdxAuthUrl holds the parts of a Salesforce CLI auth url, which has the format
+// force://<clientId>:<clientSecret>:<refreshToken>@<instanceUrl>
+type SfdxAuthUrl struct {
+	ClientId     string
+	ClientSecret string
+	RefreshToken string
+	InstanceUrl  string
+}
+
+func ParseSfdxAuthUrl(authUrl string) (SfdxAuthUrl, error) {
+	var parsed SfdxAuthUrl
+	const scheme = "force://"
+	if !strings.HasPrefix(authUrl, scheme) {
+		return parsed, fmt.Errorf("sfdx auth url must start with %s", scheme)
+	}
+	rest := strings.TrimPrefix(authUrl, scheme)
+
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return parsed, fmt.Errorf("sfdx auth url is missing the instance url, expected format is force://<clientId>:<clientSecret>:<refreshToken>@<instanceUrl>")
+	}
+	credentials, instance := rest[:at], rest[at+1:]
+
+	parts := strings.SplitN(credentials, ":", 3)
+	switch len(parts) {
+	case 3:
+		parsed.ClientId, parsed.ClientSecret, parsed.RefreshToken = parts[0], parts[1], parts[2]
+	case 2:
+		parsed.ClientId, parsed.RefreshToken = parts[0], parts[1]
+	default:
+		return parsed, fmt.Errorf("sfdx auth url is missing the refresh token, expected format is force://<clientId>:<clientSecret>:<refreshToken>@<instanceUrl>")
+	}
+	if parsed.ClientId == "" || parsed.RefreshToken == "" || instance == "" {
+		return parsed, fmt.Errorf("sfdx auth url must contain a client id, refresh token and instance url")
+	}
+
+	if !strings.HasPrefix(instance, "https://") && !strings.HasPrefix(instance, "http://") {
+		instance = "https://" + instance
+	}
+	parsed.InstanceUrl = strings.TrimSuffix(instance, "/")
+	return parsed, nil
+}
+
+func requestToken(ctx context.Context, client *http.Client, domain string, payload url.Values) (AuthResponse, error) {
+	var oauth AuthResponse
+
 	// Build Body
 	body := strings.NewReader(payload.Encode())
 
 	// Build Request
-	req, err := http.NewRequest("POST", domain+salesforceOAuthEndpoint, body)
+	req, err := http.NewRequestWithContext(ctx, "POST", domain+salesforceOAuthEndpoint, body)
 	if err != nil {
 		return oauth, fmt.Errorf("Error creating authentication request: %v", err)
 	}
@@ -68,7 +217,7 @@ func Authenticate(domain string, signedJwt string) (AuthResponse, error) {
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return oauth, fmt.Errorf("Error sending authentication request: %v", err)
 	}
@@ -95,49 +244,203 @@ func Authenticate(domain string, signedJwt string) (AuthResponse, error) {
 }
 
 type Config struct {
-	ClientId   string
-	PrivateKey string
+	// Credentials set explicitly in the provider block, see credentialChain for the other sources
+	Credentials
 	ApiVersion string
-	Username   string
-	LoginUrl   string
+	// JWTAudience overrides the audience derived from LoginUrl, see JWTAudience
+	JWTAudience string
+	// JWTExpiry is the lifetime of the signed assertion, defaults to DefaultJWTExpiry
+	JWTExpiry time.Duration
+	// HTTPClient is used for the token exchange and all REST requests, see NewHTTPClient
+	HTTPClient *http.Client
+	// TokenCache reuses access tokens between runs, stored in TokenCacheDir
+	TokenCache    bool
+	TokenCacheDir string
+	// Retry controls how REST requests failing with a transient error are retried
+	Retry rest.RetryPolicy
+
+	// cliStateDir is the auth store of the Salesforce CLI, defaults to ~/.sfdx
+	cliStateDir string
 }
 
-func Client(config Config) (*force.ForceApi, error) {
-	var privateKeyBytes []byte
-	// try to read private key as file
-	path, err := homedir.Expand(config.PrivateKey)
+func (c Config) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return &http.Client{}
+	}
+	return c.HTTPClient
+}
+
+func normalizeLoginUrl(loginUrl string) string {
+	if loginUrl == "" {
+		return productionSalesforceLoginServer
+	}
+	return strings.TrimSuffix(loginUrl, "/")
+}
+
+// readFileOrValue reads the value as a file if it points to one, otherwise the value
+// is assumed to be the content itself
+func readFileOrValue(value string) ([]byte, error) {
+	path, err := homedir.Expand(value)
 	if err != nil {
 		// don't expand then..
-		path = config.PrivateKey
+		path = value
 	}
 	if _, err := os.Stat(path); err == nil {
-		privateKeyBytes, err = os.ReadFile(path)
+		return os.ReadFile(path)
+	}
+	// if there is any os.Stat error assume the content was passed directly
+	return []byte(value), nil
+}
+
+// login runs the OAuth flow selected by the config, it is safe to call repeatedly
+// since every flow other than access_token obtains a fresh token
+func login(ctx context.Context, config Config) (AuthResponse, error) {
+	switch config.AuthType {
+	case AuthTypeJWT, "":
+		privateKeyBytes, err := readFileOrValue(config.PrivateKey)
 		if err != nil {
-			return nil, err
+			return AuthResponse{}, err
+		}
+		privateKey, err := parsePrivateKey(privateKeyBytes, config.PrivateKeyPassphrase)
+		if err != nil {
+			return AuthResponse{}, err
 		}
-	} else {
-		// if there is any os.Stat error assume the key was passed directly
-		privateKeyBytes = []byte(config.PrivateKey)
+
+		audience := config.JWTAudience
+		if audience == "" {
+			audience = JWTAudience(config.LoginUrl)
+		}
+		signedJwt, err := SignJWT(privateKey, config.Username, config.ClientId, audience, config.JWTExpiry)
+		if err != nil {
+			return AuthResponse{}, err
+		}
+
+		return Authenticate(ctx, config.HTTPClient, config.LoginUrl, signedJwt)
+	case AuthTypeClientCredentials:
+		return AuthenticateClientCredentials(ctx, config.HTTPClient, config.LoginUrl, config.ClientId, config.ClientSecret)
+	case AuthTypePassword:
+		return AuthenticatePassword(ctx, config.HTTPClient, config.LoginUrl, config.ClientId, config.ClientSecret, config.Username, config.Password, config.SecurityToken)
+	case AuthTypeRefreshToken:
+		return AuthenticateRefreshToken(ctx, config.HTTPClient, config.LoginUrl, config.ClientId, config.ClientSecret, config.RefreshToken)
+	case AuthTypeDevice:
+		return deviceLogin(ctx, config)
+	case AuthTypeAccessToken:
+		// the token was obtained elsewhere, use it as is
+		return AuthResponse{
+			AccessToken: config.AccessToken,
+			InstanceUrl: strings.TrimSuffix(config.InstanceUrl, "/"),
+		}, nil
+	default:
+		return AuthResponse{}, fmt.Errorf("unsupported auth type %q, must be one of: [%s]", config.AuthType, strings.Join(AuthTypes, ", "))
+	}
+}
+
+// cachedLogin reuses a cached access token if the identity service still accepts it,
+// otherwise it logs in and caches the new token
+func cachedLogin(ctx context.Context, config Config, cache *tokenCache) (AuthResponse, error) {
+	if cache == nil {
+		return login(ctx, config)
+	}
+
+	if token, ok := cache.get(config.Credentials); ok {
+		if tokenValid(ctx, config.HTTPClient, token) {
+			return token, nil
+		}
+		cache.delete(config.Credentials)
 	}
 
-	if config.LoginUrl == "" {
-		config.LoginUrl = productionSalesforceLoginServer
+	return freshLogin(ctx, config, cache)
+}
+
+// freshLogin always logs in and caches the new token if the cache is enabled
+func freshLogin(ctx context.Context, config Config, cache *tokenCache) (AuthResponse, error) {
+	resp, err := login(ctx, config)
+	if err != nil || cache == nil {
+		return resp, err
 	}
-	config.LoginUrl = strings.TrimSuffix(config.LoginUrl, "/")
+	// a failure to write the cache only means the next run logs in again
+	_ = cache.put(config.Credentials, resp)
+	return resp, nil
+}
 
-	signedJwt, err := SignJWT(privateKeyBytes, config.Username, config.ClientId, config.LoginUrl)
+func Client(ctx context.Context, config Config) (*rest.Client, error) {
+	creds, err := resolveCredentials(ctx, config)
 	if err != nil {
 		return nil, err
 	}
+	config.Credentials = creds
+
+	if config.SfdxAuthUrl != "" {
+		// the auth URL carries the credentials of the refresh token flow, it can't complete another flow
+		if config.AuthType != AuthTypeRefreshToken {
+			return nil, fmt.Errorf("sfdx_auth_url can only be used with the %s auth type, auth_type is %s", AuthTypeRefreshToken, config.AuthType)
+		}
+		sfdx, err := ParseSfdxAuthUrl(config.SfdxAuthUrl)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientId = sfdx.ClientId
+		config.ClientSecret = sfdx.ClientSecret
+		config.RefreshToken = sfdx.RefreshToken
+		config.LoginUrl = sfdx.InstanceUrl
+	}
+
+	config.LoginUrl = normalizeLoginUrl(config.LoginUrl)
+	config.HTTPClient = config.httpClient()
+
+	var cache *tokenCache
+	if config.TokenCache && config.AuthType != AuthTypeAccessToken {
+		var err error
+		cache, err = newTokenCache(config.TokenCacheDir)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := Authenticate(config.LoginUrl, signedJwt)
+	resp, err := cachedLogin(ctx, config, cache)
 	if err != nil {
 		return nil, err
 	}
 
-	apiVersion := config.ApiVersion
-	if !strings.HasPrefix(apiVersion, "v") {
-		apiVersion = "v" + apiVersion
+	if config.AuthType == AuthTypeDevice && resp.RefreshToken != "" {
+		// renew an expired session with the refresh token instead of asking the user again
+		config.AuthType = AuthTypeRefreshToken
+		config.RefreshToken = resp.RefreshToken
+	}
+	sess := &session{accessToken: resp.AccessToken}
+	if missing := config.missing(); len(missing) > 0 {
+		// the credentials were completed by a cached token, there is not enough to log in again
+		sess.login = func(context.Context) (AuthResponse, error) {
+			return AuthResponse{}, fmt.Errorf("the cached Salesforce session expired, the %s auth type requires %s to be set to log in again", config.AuthType, strings.Join(missing, ", "))
+		}
+	} else if config.AuthType != AuthTypeAccessToken && config.AuthType != AuthTypeDevice {
+		sess.login = func(ctx context.Context) (AuthResponse, error) {
+			return freshLogin(ctx, config, cache)
+		}
+	}
+	// the REST client shares the transport of the token exchange, requests are authorized
+	// by the session so expired sessions are renewed transparently
+	base := config.HTTPClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	restClient := &http.Client{
+		Transport: &sessionTransport{
+			base:    base,
+			session: sess,
+		},
+		Timeout: config.HTTPClient.Timeout,
+	}
+
+	supported, err := supportedApiVersions(ctx, restClient, resp.InstanceUrl)
+	if err != nil {
+		return nil, &ApiVersionError{Err: err}
+	}
+	apiVersion, err := resolveApiVersion(config.ApiVersion, supported)
+	if err != nil {
+		return nil, &ApiVersionError{Err: err}
 	}
-	return force.CreateWithAccessToken(apiVersion, config.ClientId, resp.AccessToken, resp.InstanceUrl)
+	client := rest.NewClient(restClient, resp.InstanceUrl, apiVersion, config.Retry)
+	client.SetIdentityUrl(resp.Id)
+	return client, nil
 }
@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const tokenCacheDirName = "terraform-provider-salesforce"
+
+// tokenCache persists access tokens between runs, one file per org, connected app, auth type and
+// identity the flow logs in as, see tokenCacheKey
+type tokenCache struct {
+	dir string
+}
+
+// newTokenCache creates the cache directory readable only by the current user, dir
+// defaults to the user cache directory, such as ~/.cache/terraform-provider-salesforce
+func newTokenCache(dir string) (*tokenCache, error) {
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the user cache directory, set token_cache_dir: %v", err)
+		}
+		dir = filepath.Join(userCacheDir, tokenCacheDirName)
+	} else if expanded, err := homedir.Expand(dir); err == nil {
+		dir = expanded
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create token cache directory: %v", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to restrict token cache directory permissions: %v", err)
+	}
+	return &tokenCache{dir: dir}, nil
+}
+
+// tokenCacheKey identifies the user a token was issued to: the username for the jwt and password
+// flows, the refresh token for the refresh_token flow, and the connected app alone for the client
+// credentials flow, which always runs as the user set on the app
+func tokenCacheKey(creds Credentials) string {
+	return strings.Join([]string{
+		normalizeLoginUrl(creds.LoginUrl),
+		creds.ClientId,
+		creds.ResolvedAuthType(),
+		creds.Username,
+		creds.RefreshToken,
+	}, "\n")
+}
+
+func (c *tokenCache) path(creds Credentials) string {
+	sum := sha256.Sum256([]byte(tokenCacheKey(creds)))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *tokenCache) get(creds Credentials) (AuthResponse, bool) {
+	var token AuthResponse
+	b, err := os.ReadFile(c.path(creds))
+	if err != nil {
+		return token, false
+	}
+	if err := json.Unmarshal(b, &token); err != nil || token.AccessToken == "" || token.InstanceUrl == "" {
+		return token, false
+	}
+	return token, true
+}
+
+func (c *tokenCache) put(creds Credentials, token AuthResponse) error {
+	// only the short lived access token is cached, never the refresh token
+	token.RefreshToken = ""
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so concurrent runs never read a partial token,
+	// CreateTemp creates the file with 0600 permissions
+	f, err := os.CreateTemp(c.dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(creds))
+}
+
+func (c *tokenCache) delete(creds Credentials) {
+	os.Remove(c.path(creds))
+}
+
+// tokenValid asks the identity service whether the access token is still accepted
+func tokenValid(ctx context.Context, client *http.Client, token AuthResponse) bool {
+	if token.Id == "" {
+		return false
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", token.Id, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
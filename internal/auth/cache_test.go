@@ -0,0 +1,129 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTokenCache_permissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache, err := newTokenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := Credentials{AuthType: AuthTypeJWT, ClientId: "id", Username: "user@example.com"}
+	if err := cache.put(creds, AuthResponse{AccessToken: "token", InstanceUrl: "https://example.my.salesforce.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("expected directory permissions 0700, got %o", perm)
+	}
+	info, err = os.Stat(cache.path(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected file permissions 0600, got %o", perm)
+	}
+
+	if _, ok := cache.get(creds); !ok {
+		t.Error("expected cache hit")
+	}
+}
+
+func TestTokenCache_key(t *testing.T) {
+	cache, err := newTokenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds := Credentials{AuthType: AuthTypeRefreshToken, ClientId: "id", RefreshToken: "refresh1"}
+	if err := cache.put(creds, AuthResponse{AccessToken: "token", InstanceUrl: "https://example.my.salesforce.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.get(Credentials{ClientId: "id", RefreshToken: "refresh1", LoginUrl: "https://login.salesforce.com/"}); !ok {
+		t.Error("expected cache hit for the same credentials with the default auth type and login url")
+	}
+
+	for name, other := range map[string]Credentials{
+		"refresh token": {AuthType: AuthTypeRefreshToken, ClientId: "id", RefreshToken: "refresh2"},
+		"auth type":     {AuthType: AuthTypeClientCredentials, ClientId: "id"},
+		"username":      {AuthType: AuthTypeJWT, ClientId: "id", Username: "other@example.com"},
+		"login url":     {AuthType: AuthTypeRefreshToken, ClientId: "id", RefreshToken: "refresh1", LoginUrl: "https://test.salesforce.com"},
+	} {
+		if _, ok := cache.get(other); ok {
+			t.Errorf("expected cache miss for another %s", name)
+		}
+	}
+}
+
+func TestCachedLogin(t *testing.T) {
+	var logins int32
+	var tokenAccepted int32 = 1
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case salesforceOAuthEndpoint:
+			n := atomic.AddInt32(&logins, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token%d","instance_url":"%s","id":"%s/id/00D/005"}`, n, server.URL, server.URL)
+		case "/id/00D/005":
+			if atomic.LoadInt32(&tokenAccepted) == 0 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"user_id":"005"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := newTokenCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{
+		Credentials: Credentials{
+			AuthType:     AuthTypeClientCredentials,
+			ClientId:     "id",
+			ClientSecret: "secret",
+			LoginUrl:     server.URL,
+		},
+		HTTPClient: server.Client(),
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := cachedLogin(context.Background(), config, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.AccessToken != "token1" {
+			t.Errorf("expected cached token1, got %s", resp.AccessToken)
+		}
+	}
+
+	atomic.StoreInt32(&tokenAccepted, 0)
+	resp, err := cachedLogin(context.Background(), config, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.AccessToken != "token2" {
+		t.Errorf("expected a fresh token2 after the cached one was rejected, got %s", resp.AccessToken)
+	}
+	if logins != 2 {
+		t.Errorf("expected 2 logins, got %d", logins)
+	}
+}
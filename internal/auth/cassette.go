@@ -0,0 +1,228 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// CassetteEnv is the path of the cassette file and CassetteModeEnv selects whether it is
+	// recorded or replayed, all requests to Salesforce go through the cassette when both are set
+	CassetteEnv     = "SALESFORCE_CASSETTE"
+	CassetteModeEnv = "SALESFORCE_CASSETTE_MODE"
+
+	// CassetteModeRecord sends the requests to Salesforce and appends every exchange to the cassette
+	CassetteModeRecord = "record"
+	// CassetteModeReplay answers the requests from the cassette without any network access
+	CassetteModeReplay = "replay"
+)
+
+// cassetteHeaders are the response headers kept in a cassette, the others vary between runs
+// or may identify the session
+var cassetteHeaders = []string{"Content-Type", "Location", "Retry-After", "Sforce-Limit-Info"}
+
+type cassette struct {
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// cassetteRequest identifies a request, it is stored with its secrets scrubbed and replayed
+// requests are scrubbed the same way before they are matched
+type cassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type cassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// CassetteTransport records the exchanges with Salesforce, the OAuth token requests as well as
+// the REST API, or replays them. Access tokens, assertions, passwords and other secrets are
+// masked as in the logs, request headers are never recorded
+type CassetteTransport struct {
+	mode string
+	path string
+	base http.RoundTripper
+
+	mu       sync.Mutex
+	cassette cassette
+	used     []bool
+}
+
+// NewCassetteTransport records the requests sent through base to the cassette at path, or
+// replays the cassette when mode is CassetteModeReplay, in which case base is not used
+func NewCassetteTransport(mode string, path string, base http.RoundTripper) (*CassetteTransport, error) {
+	t := &CassetteTransport{mode: mode, path: path, base: base}
+	switch mode {
+	case CassetteModeRecord:
+		if base == nil {
+			t.base = http.DefaultTransport
+		}
+	case CassetteModeReplay:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read cassette: %v", err)
+		}
+		if err := json.Unmarshal(b, &t.cassette); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+		}
+		t.used = make([]bool, len(t.cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("invalid %s %q, expected %s or %s", CassetteModeEnv, mode, CassetteModeRecord, CassetteModeReplay)
+	}
+	return t, nil
+}
+
+// cassetteFromEnv returns the cassette transport selected by the environment, nil when the
+// cassette is not enabled
+func cassetteFromEnv(base http.RoundTripper) (http.RoundTripper, error) {
+	path, mode := os.Getenv(CassetteEnv), os.Getenv(CassetteModeEnv)
+	if path == "" && mode == "" {
+		return nil, nil
+	}
+	if path == "" {
+		return nil, fmt.Errorf("%s must be set together with %s", CassetteEnv, CassetteModeEnv)
+	}
+	if mode == "" {
+		mode = CassetteModeReplay
+	}
+	return NewCassetteTransport(mode, path, base)
+}
+
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	key := cassetteRequest{
+		Method: req.Method,
+		URL:    scrubUrl(req),
+		Body:   redact(string(reqBody)),
+	}
+
+	if t.mode == CassetteModeReplay {
+		return t.replay(req, key)
+	}
+	return t.record(req, key)
+}
+
+// replay answers with the first exchange of the cassette matching the request that was not
+// replayed yet, so repeated requests get the responses in the order they were recorded
+func (t *CassetteTransport) replay(req *http.Request, key cassetteRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, recorded := range t.cassette.Interactions {
+		if t.used[i] || recorded.Request != key {
+			continue
+		}
+		t.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", recorded.Response.StatusCode, http.StatusText(recorded.Response.StatusCode)),
+			StatusCode:    recorded.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        recorded.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(recorded.Response.Body)),
+			ContentLength: int64(len(recorded.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("cassette %s has no recorded response left for %s %s", t.path, key.Method, key.URL)
+}
+
+func (t *CassetteTransport) record(req *http.Request, key cassetteRequest) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	recorded := interaction{
+		Request: key,
+		Response: cassetteResponse{
+			StatusCode: resp.StatusCode,
+			Header:     http.Header{},
+			Body:       redact(string(respBody)),
+		},
+	}
+	for _, name := range cassetteHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			recorded.Response.Header[name] = values
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, recorded)
+	// the cassette is saved after every exchange, the provider process has no shutdown hook
+	if err := t.save(); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unable to save cassette: %v", err)
+	}
+	return resp, nil
+}
+
+func (t *CassetteTransport) save() error {
+	// bodies are kept readable, form bodies and queries would be full of \u0026 otherwise
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(t.cassette); err != nil {
+		return err
+	}
+	// write to a temporary file first so an interrupted run never leaves a partial cassette,
+	// CreateTemp creates the file with 0600 permissions
+	f, err := os.CreateTemp(filepath.Dir(t.path), ".cassette-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b.Bytes()); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), t.path)
+}
+
+// scrubUrl returns the url of the request without userinfo and with secrets in the query masked
+func scrubUrl(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	u.RawQuery = redact(u.RawQuery)
+	return u.String()
+}
@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "sid=00D000000000001!cookie")
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			_, _ = w.Write([]byte(`{"access_token":"00D000000000001!AQ4AQH0dMHZfz972Szmpkb58urFRkgeBGsxL","instance_url":"https://example.my.salesforce.com","id":"https://login.salesforce.com/id/00D000000000001AAA/005000000000001AAA"}`))
+		case "/services/data/v55.0/sobjects/User/005000000000001AAA":
+			_, _ = w.Write([]byte(`{"attributes":{"type":"User"},"LastName":"Doe"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	ctx := context.Background()
+
+	// the exchanges are the OAuth token request and a REST API request
+	exchange := func(client *http.Client) []string {
+		t.Helper()
+		form := url.Values{"grant_type": {"password"}, "client_secret": {"s3cr3t-value"}, "password": {"hunter2"}}
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/services/oauth2/token", strings.NewReader(form.Encode()))
+		token, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenBody, _ := io.ReadAll(token.Body)
+		token.Body.Close()
+
+		req, _ = http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/services/data/v55.0/sobjects/User/005000000000001AAA", nil)
+		req.Header.Set("Authorization", "Bearer 00D000000000001!AQ4AQH0dMHZfz972Szmpkb58urFRkgeBGsxL")
+		user, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		userBody, _ := io.ReadAll(user.Body)
+		user.Body.Close()
+		if user.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected the content type to be kept, got %v", user.Header)
+		}
+		return []string{string(tokenBody), string(userBody)}
+	}
+
+	recorder, err := NewCassetteTransport(CassetteModeRecord, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := exchange(&http.Client{Transport: recorder})
+	if !strings.Contains(recorded[0], "AQ4AQH0dMHZfz972Szmpkb58urFRkgeBGsxL") {
+		t.Errorf("expected the live response to be returned unchanged while recording, got %s", recorded[0])
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"AQ4AQH0dMHZfz972Szmpkb58urFRkgeBGsxL", "hunter2", "s3cr3t-value", "cookie", "Bearer"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, b)
+		}
+	}
+
+	// replaying doesn't need the server anymore
+	server.Close()
+	player, err := NewCassetteTransport(CassetteModeReplay, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: player}
+	replayed := exchange(client)
+	if !strings.Contains(replayed[0], `"access_token":"***"`) || !strings.Contains(replayed[0], `"instance_url":"https://example.my.salesforce.com"`) {
+		t.Errorf("unexpected replayed token response %s", replayed[0])
+	}
+	if replayed[1] != recorded[1] {
+		t.Errorf("expected %s, got %s", recorded[1], replayed[1])
+	}
+
+	// every exchange is replayed once
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/services/data/v55.0/sobjects/User/005000000000001AAA", nil)
+	if _, err := client.Do(req); err == nil || !strings.Contains(err.Error(), "no recorded response left") {
+		t.Errorf("expected the cassette to be exhausted, got %v", err)
+	}
+}
+
+func TestCassetteFromEnv(t *testing.T) {
+	t.Setenv(CassetteEnv, "")
+	t.Setenv(CassetteModeEnv, "")
+	if transport, err := cassetteFromEnv(nil); transport != nil || err != nil {
+		t.Errorf("expected no cassette, got %v %v", transport, err)
+	}
+
+	t.Setenv(CassetteModeEnv, CassetteModeRecord)
+	if _, err := cassetteFromEnv(nil); err == nil {
+		t.Error("expected an error without cassette path")
+	}
+
+	t.Setenv(CassetteEnv, filepath.Join(t.TempDir(), "cassette.json"))
+	t.Setenv(CassetteModeEnv, "rewind")
+	if _, err := cassetteFromEnv(nil); err == nil {
+		t.Error("expected an error for an invalid mode")
+	}
+
+	t.Setenv(CassetteModeEnv, CassetteModeRecord)
+	client, err := NewHTTPClient(TransportConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.Transport.(*loggingTransport).base.(*CassetteTransport); !ok {
+		t.Errorf("expected the cassette to be installed, got %T", client.Transport.(*loggingTransport).base)
+	}
+}
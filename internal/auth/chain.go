@@ -0,0 +1,257 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Credentials identify the user and the OAuth flow used to authenticate, they are gathered
+// from the sources of the credential chain
+type Credentials struct {
+	AuthType             string
+	ClientId             string
+	ClientSecret         string
+	PrivateKey           string
+	PrivateKeyPassphrase string
+	Username             string
+	Password             string
+	SecurityToken        string
+	RefreshToken         string
+	SfdxAuthUrl          string
+	CliOrgAlias          string
+	AccessToken          string
+	InstanceUrl          string
+	LoginUrl             string
+}
+
+// CredentialsFromEnv reads the credentials from the SALESFORCE_* environment variables
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AuthType:             os.Getenv("SALESFORCE_AUTH_TYPE"),
+		ClientId:             os.Getenv("SALESFORCE_CLIENT_ID"),
+		ClientSecret:         os.Getenv("SALESFORCE_CLIENT_SECRET"),
+		PrivateKey:           os.Getenv("SALESFORCE_PRIVATE_KEY"),
+		PrivateKeyPassphrase: os.Getenv("SALESFORCE_PRIVATE_KEY_PASSPHRASE"),
+		Username:             os.Getenv("SALESFORCE_USERNAME"),
+		Password:             os.Getenv("SALESFORCE_PASSWORD"),
+		SecurityToken:        os.Getenv("SALESFORCE_SECURITY_TOKEN"),
+		RefreshToken:         os.Getenv("SALESFORCE_REFRESH_TOKEN"),
+		SfdxAuthUrl:          os.Getenv("SALESFORCE_SFDX_AUTH_URL"),
+		CliOrgAlias:          os.Getenv("SALESFORCE_CLI_ORG_ALIAS"),
+		AccessToken:          os.Getenv("SALESFORCE_ACCESS_TOKEN"),
+		InstanceUrl:          os.Getenv("SALESFORCE_INSTANCE_URL"),
+		LoginUrl:             os.Getenv("SALESFORCE_LOGIN_URL"),
+	}
+}
+
+// merge fills the empty fields of c with the values of other
+func (c Credentials) merge(other Credentials) Credentials {
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	fill(&c.AuthType, other.AuthType)
+	fill(&c.ClientId, other.ClientId)
+	fill(&c.ClientSecret, other.ClientSecret)
+	fill(&c.PrivateKey, other.PrivateKey)
+	fill(&c.PrivateKeyPassphrase, other.PrivateKeyPassphrase)
+	fill(&c.Username, other.Username)
+	fill(&c.Password, other.Password)
+	fill(&c.SecurityToken, other.SecurityToken)
+	fill(&c.RefreshToken, other.RefreshToken)
+	fill(&c.SfdxAuthUrl, other.SfdxAuthUrl)
+	fill(&c.CliOrgAlias, other.CliOrgAlias)
+	fill(&c.AccessToken, other.AccessToken)
+	fill(&c.InstanceUrl, other.InstanceUrl)
+	fill(&c.LoginUrl, other.LoginUrl)
+	return c
+}
+
+// ResolvedAuthType returns the configured auth type, or infers it from the credentials that are set
+func (c Credentials) ResolvedAuthType() string {
+	switch {
+	case c.AuthType != "":
+		return c.AuthType
+	case c.AccessToken != "":
+		return AuthTypeAccessToken
+	case c.RefreshToken != "" || c.SfdxAuthUrl != "" || c.CliOrgAlias != "":
+		return AuthTypeRefreshToken
+	default:
+		return AuthTypeJWT
+	}
+}
+
+// missing lists the attributes required by the auth type that are not set
+func (c Credentials) missing() []string {
+	var missing []string
+	require := func(attr string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				return
+			}
+		}
+		missing = append(missing, attr)
+	}
+
+	switch c.ResolvedAuthType() {
+	case AuthTypeJWT:
+		require("client_id", c.ClientId)
+		require("private_key", c.PrivateKey)
+		require("username", c.Username)
+	case AuthTypeClientCredentials:
+		require("client_id", c.ClientId)
+		require("client_secret", c.ClientSecret)
+	case AuthTypePassword:
+		require("client_id", c.ClientId)
+		require("client_secret", c.ClientSecret)
+		require("username", c.Username)
+		require("password", c.Password)
+	case AuthTypeRefreshToken:
+		// a cli_org_alias is only complete once the CLI source resolved it, it takes precedence
+		// over sfdx_auth_url
+		sfdxAuthUrl := c.SfdxAuthUrl
+		if c.CliOrgAlias != "" {
+			sfdxAuthUrl = ""
+		}
+		require("client_id", c.ClientId, sfdxAuthUrl)
+		require("refresh_token", c.RefreshToken, sfdxAuthUrl)
+	case AuthTypeDevice:
+		require("client_id", c.ClientId)
+	case AuthTypeAccessToken:
+		require("access_token", c.AccessToken)
+		require("instance_url", c.InstanceUrl)
+	}
+	return missing
+}
+
+// credentialSource is a step of the credential chain, it receives the credentials gathered
+// by the previous steps and returns them completed with what the source knows about
+type credentialSource struct {
+	name    string
+	resolve func(ctx context.Context, prev Credentials) (Credentials, error)
+	// complete sources need no more credentials than they return, such as a cached token
+	complete bool
+}
+
+// NoCredentialsError is returned when no source of the credential chain provided a complete set of credentials
+type NoCredentialsError struct {
+	Attempts []string
+}
+
+func (e *NoCredentialsError) Error() string {
+	return fmt.Sprintf("No complete set of credentials was found, the following sources were tried in order:\n  - %s", strings.Join(e.Attempts, "\n  - "))
+}
+
+// credentialChain lists the sources in order of precedence: the provider block, the environment,
+// an org authorized with the Salesforce CLI, and finally a token left in the token cache
+func credentialChain(config Config) []credentialSource {
+	return []credentialSource{
+		{
+			name: "provider configuration",
+			resolve: func(_ context.Context, prev Credentials) (Credentials, error) {
+				return prev.merge(config.Credentials), nil
+			},
+		},
+		{
+			name: "environment variables",
+			resolve: func(_ context.Context, prev Credentials) (Credentials, error) {
+				return prev.merge(CredentialsFromEnv()), nil
+			},
+		},
+		{
+			name: "Salesforce CLI",
+			resolve: func(_ context.Context, prev Credentials) (Credentials, error) {
+				if prev.CliOrgAlias == "" {
+					return prev, fmt.Errorf("cli_org_alias is not set")
+				}
+				if prev.AuthType != "" && prev.AuthType != AuthTypeRefreshToken {
+					return prev, fmt.Errorf("cli_org_alias can only be used with the %s auth type, auth_type is %s", AuthTypeRefreshToken, prev.AuthType)
+				}
+				org, err := CliOrg(config.cliStateDir, prev.CliOrgAlias)
+				if err != nil {
+					return prev, err
+				}
+				// the org of the alias replaces the one of sfdx_auth_url
+				prev.SfdxAuthUrl = ""
+				prev.AuthType = AuthTypeRefreshToken
+				prev.ClientId = org.ClientId
+				prev.ClientSecret = org.ClientSecret
+				prev.RefreshToken = org.RefreshToken
+				prev.LoginUrl = org.InstanceUrl
+				return prev, nil
+			},
+		},
+		{
+			name: "token cache",
+			resolve: func(ctx context.Context, prev Credentials) (Credentials, error) {
+				if !config.TokenCache {
+					return prev, fmt.Errorf("token_cache is not enabled")
+				}
+				cache, err := newTokenCache(config.TokenCacheDir)
+				if err != nil {
+					return prev, err
+				}
+				token, ok := cache.get(prev)
+				if !ok {
+					return prev, fmt.Errorf("no token cached for the %s auth type with login_url %s, client_id %q and username %q", prev.ResolvedAuthType(), normalizeLoginUrl(prev.LoginUrl), prev.ClientId, prev.Username)
+				}
+				if !tokenValid(ctx, config.httpClient(), token) {
+					cache.delete(prev)
+					return prev, fmt.Errorf("the cached token is no longer valid")
+				}
+				// the credentials stay those of the flow that cached the token, the client reuses the
+				// token and logs in with them again once it expires
+				prev.AuthType = prev.ResolvedAuthType()
+				return prev, nil
+			},
+			complete: true,
+		},
+	}
+}
+
+// resolveCredentials walks the credential chain until a source completes the credentials
+func resolveCredentials(ctx context.Context, config Config) (Credentials, error) {
+	var creds Credentials
+	var attempts []string
+	for _, source := range credentialChain(config) {
+		next, err := source.resolve(ctx, creds)
+		if err != nil {
+			tflog.Debug(ctx, "Salesforce credential source skipped", map[string]interface{}{"source": source.name, "reason": err.Error()})
+			attempts = append(attempts, fmt.Sprintf("%s: %s", source.name, err))
+			continue
+		}
+		creds = next
+
+		authType := creds.ResolvedAuthType()
+		if !isAuthType(authType) {
+			return creds, fmt.Errorf("unsupported auth type %q set in %s, must be one of: [%s]", authType, source.name, strings.Join(AuthTypes, ", "))
+		}
+		if missing := creds.missing(); len(missing) > 0 && !source.complete {
+			tflog.Debug(ctx, "Salesforce credential source incomplete", map[string]interface{}{"source": source.name, "auth_type": authType, "missing": missing})
+			attempts = append(attempts, fmt.Sprintf("%s: the %s auth type requires %s to be set", source.name, authType, strings.Join(missing, ", ")))
+			continue
+		}
+
+		tflog.Info(ctx, "Selected Salesforce credentials", map[string]interface{}{"source": source.name, "auth_type": authType})
+		creds.AuthType = authType
+		return creds, nil
+	}
+	return creds, &NoCredentialsError{Attempts: attempts}
+}
+
+func isAuthType(authType string) bool {
+	for _, t := range AuthTypes {
+		if t == authType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResolveCredentials(t *testing.T) {
+	cases := map[string]struct {
+		config   Credentials
+		env      map[string]string
+		expected Credentials
+		attempts []string
+	}{
+		"provider configuration": {
+			config:   Credentials{ClientId: "id", PrivateKey: "key", Username: "user@example.com"},
+			env:      map[string]string{"SALESFORCE_USERNAME": "env@example.com"},
+			expected: Credentials{AuthType: AuthTypeJWT, ClientId: "id", PrivateKey: "key", Username: "user@example.com"},
+		},
+		"completed by environment": {
+			config:   Credentials{AuthType: AuthTypeClientCredentials, ClientId: "id"},
+			env:      map[string]string{"SALESFORCE_CLIENT_ID": "env", "SALESFORCE_CLIENT_SECRET": "secret"},
+			expected: Credentials{AuthType: AuthTypeClientCredentials, ClientId: "id", ClientSecret: "secret"},
+		},
+		"auth type inferred from environment": {
+			env:      map[string]string{"SALESFORCE_ACCESS_TOKEN": "token", "SALESFORCE_INSTANCE_URL": "https://example.my.salesforce.com"},
+			expected: Credentials{AuthType: AuthTypeAccessToken, AccessToken: "token", InstanceUrl: "https://example.my.salesforce.com"},
+		},
+		"cli org alias with another auth type": {
+			config: Credentials{AuthType: AuthTypeJWT, ClientId: "id", CliOrgAlias: "dev"},
+			attempts: []string{
+				"provider configuration: the jwt auth type requires private_key, username to be set",
+				"environment variables: the jwt auth type requires private_key, username to be set",
+				"Salesforce CLI: cli_org_alias can only be used with the refresh_token auth type, auth_type is jwt",
+				"token cache: token_cache is not enabled",
+			},
+		},
+		"cli org alias over sfdx auth url": {
+			config: Credentials{CliOrgAlias: "dev", SfdxAuthUrl: "force://3MVG9sfdx:sfdxSecret:5Aep861sfdxRefreshToken@https://sfdx.my.salesforce.com"},
+			expected: Credentials{
+				AuthType:     AuthTypeRefreshToken,
+				ClientId:     "PlatformCLI",
+				RefreshToken: "5Aep861cliRefreshToken",
+				LoginUrl:     "https://dev.my.salesforce.com",
+				CliOrgAlias:  "dev",
+			},
+		},
+		"nothing found": {
+			config: Credentials{AuthType: AuthTypePassword, ClientId: "id"},
+			attempts: []string{
+				"provider configuration: the password auth type requires client_secret, username, password to be set",
+				"environment variables: the password auth type requires client_secret, username, password to be set",
+				"Salesforce CLI: cli_org_alias is not set",
+				"token cache: token_cache is not enabled",
+			},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			for _, env := range []string{
+				"SALESFORCE_AUTH_TYPE", "SALESFORCE_CLIENT_ID", "SALESFORCE_CLIENT_SECRET", "SALESFORCE_PRIVATE_KEY",
+				"SALESFORCE_PRIVATE_KEY_PASSPHRASE", "SALESFORCE_USERNAME", "SALESFORCE_PASSWORD", "SALESFORCE_SECURITY_TOKEN",
+				"SALESFORCE_REFRESH_TOKEN", "SALESFORCE_SFDX_AUTH_URL", "SALESFORCE_CLI_ORG_ALIAS", "SALESFORCE_ACCESS_TOKEN",
+				"SALESFORCE_INSTANCE_URL", "SALESFORCE_LOGIN_URL",
+			} {
+				t.Setenv(env, c.env[env])
+			}
+
+			creds, err := resolveCredentials(context.Background(), Config{Credentials: c.config, cliStateDir: "testdata/sfdx"})
+			if c.attempts != nil {
+				var noCredentials *NoCredentialsError
+				if !errors.As(err, &noCredentials) {
+					t.Fatalf("expected NoCredentialsError, got %v", err)
+				}
+				if strings.Join(noCredentials.Attempts, "\n") != strings.Join(c.attempts, "\n") {
+					t.Errorf("expected attempts:\n%s\ngot:\n%s", strings.Join(c.attempts, "\n"), strings.Join(noCredentials.Attempts, "\n"))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds != c.expected {
+				t.Errorf("expected %#v, got %#v", c.expected, creds)
+			}
+		})
+	}
+}
+
+func TestClient_tokenCache(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/id/00D/005":
+			_, _ = w.Write([]byte(`{"user_id":"005"}`))
+		case salesforceVersionsEndpoint:
+			_, _ = w.Write([]byte(`[{"label":"Summer '22","url":"/services/data/v55.0","version":"55.0"}]`))
+		case "/services/data/v55.0/limits":
+			// the cached token expires after the client was created
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	for _, env := range []string{"SALESFORCE_AUTH_TYPE", "SALESFORCE_CLIENT_ID", "SALESFORCE_CLIENT_SECRET", "SALESFORCE_CLI_ORG_ALIAS", "SALESFORCE_ACCESS_TOKEN"} {
+		t.Setenv(env, "")
+	}
+
+	dir := t.TempDir()
+	cache, err := newTokenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cache.put(Credentials{AuthType: AuthTypeClientCredentials, ClientId: "id", LoginUrl: server.URL}, AuthResponse{
+		AccessToken: "cached",
+		InstanceUrl: server.URL,
+		Id:          server.URL + "/id/00D/005",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the client secret of the flow that cached the token is no longer available
+	config := Config{
+		Credentials:   Credentials{AuthType: AuthTypeClientCredentials, ClientId: "id", LoginUrl: server.URL},
+		TokenCache:    true,
+		TokenCacheDir: dir,
+		HTTPClient:    server.Client(),
+	}
+
+	creds, err := resolveCredentials(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AuthType != AuthTypeClientCredentials || creds.ClientId != "id" {
+		t.Errorf("expected the credentials of the cached flow, got %#v", creds)
+	}
+
+	client, err := Client(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Do(context.Background(), http.MethodGet, "/services/data/v55.0/limits", nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), fmt.Sprintf("the %s auth type requires client_secret", AuthTypeClientCredentials)) {
+		t.Errorf("expected the login to require the client secret, got %v", err)
+	}
+}
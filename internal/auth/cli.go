@@ -0,0 +1,188 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// defaultCliStateDir is where the Salesforce CLI (sf and sfdx) keeps its auth store
+	defaultCliStateDir = "~/.sfdx"
+	// defaultCliClientId is the connected app used by the Salesforce CLI for web logins
+	defaultCliClientId = "PlatformCLI"
+)
+
+type cliAliases struct {
+	Orgs map[string]string `json:"orgs"`
+}
+
+type cliAuthFile struct {
+	Username     string `json:"username"`
+	OrgId        string `json:"orgId"`
+	InstanceUrl  string `json:"instanceUrl"`
+	LoginUrl     string `json:"loginUrl"`
+	ClientId     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type cliKeyFile struct {
+	Key string `json:"key"`
+}
+
+// CliOrg resolves an alias or username of an org authorized with the Salesforce CLI to the
+// client and refresh token stored for it. stateDir defaults to ~/.sfdx when empty.
+func CliOrg(stateDir string, aliasOrUsername string) (SfdxAuthUrl, error) {
+	var org SfdxAuthUrl
+	if stateDir == "" {
+		stateDir = defaultCliStateDir
+	}
+	dir, err := homedir.Expand(stateDir)
+	if err != nil {
+		return org, fmt.Errorf("unable to expand Salesforce CLI directory %s: %v", stateDir, err)
+	}
+
+	username := aliasOrUsername
+	var aliases cliAliases
+	if err := readJSONFile(filepath.Join(dir, "alias.json"), &aliases); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return org, err
+	}
+	if u, ok := aliases.Orgs[aliasOrUsername]; ok {
+		username = u
+	}
+
+	var authFile cliAuthFile
+	if err := readJSONFile(filepath.Join(dir, username+".json"), &authFile); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return org, fmt.Errorf("no Salesforce CLI org found for %q in %s, log in with `sf org login web --alias %s` first", aliasOrUsername, dir, aliasOrUsername)
+		}
+		return org, err
+	}
+	if authFile.RefreshToken == "" {
+		return org, fmt.Errorf("the Salesforce CLI org %q has no refresh token, only orgs authorized with a web or sfdx url login are supported", aliasOrUsername)
+	}
+
+	org.ClientId = authFile.ClientId
+	if org.ClientId == "" {
+		org.ClientId = defaultCliClientId
+	}
+	org.InstanceUrl = strings.TrimSuffix(authFile.InstanceUrl, "/")
+	if org.InstanceUrl == "" {
+		org.InstanceUrl = strings.TrimSuffix(authFile.LoginUrl, "/")
+	}
+
+	// secrets in the auth files are encrypted unless the CLI was told otherwise
+	org.RefreshToken, err = decryptCliValue(dir, authFile.RefreshToken)
+	if err != nil {
+		return org, fmt.Errorf("unable to decrypt the refresh token of Salesforce CLI org %q: %v", aliasOrUsername, err)
+	}
+	org.ClientSecret, err = decryptCliValue(dir, authFile.ClientSecret)
+	if err != nil {
+		return org, fmt.Errorf("unable to decrypt the client secret of Salesforce CLI org %q: %v", aliasOrUsername, err)
+	}
+	return org, nil
+}
+
+func readJSONFile(path string, out interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+	return nil
+}
+
+// isCliEncrypted reports whether the value has the <iv><ciphertext>:<tag> hex format used by the CLI
+func isCliEncrypted(value string) bool {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 || len(parts[1]) != 32 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := hex.DecodeString(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// decryptCliValue decrypts an AES-256-GCM value written by the Salesforce CLI, using the key of
+// the generic keychain (key.json) which the CLI uses on Linux or with SFDX_USE_GENERIC_UNIX_KEYCHAIN
+func decryptCliValue(dir string, value string) (string, error) {
+	if !isCliEncrypted(value) {
+		return value, nil
+	}
+
+	var keyFile cliKeyFile
+	if err := readJSONFile(filepath.Join(dir, "key.json"), &keyFile); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("the encryption key is stored in the OS keychain which can't be read by the provider, set SFDX_USE_GENERIC_UNIX_KEYCHAIN=true and log in again, or use sfdx_auth_url instead")
+		}
+		return "", err
+	}
+
+	// older CLI versions use a 32 character key and a 12 character iv as raw bytes, newer
+	// versions hex encode a 32 byte key and a 12 byte iv
+	var key, iv []byte
+	var err error
+	data := strings.Split(value, ":")
+	switch len(keyFile.Key) {
+	case 32:
+		if len(data[0]) < 12 {
+			return "", fmt.Errorf("encrypted value is too short")
+		}
+		key = []byte(keyFile.Key)
+		iv = []byte(data[0][:12])
+		data[0] = data[0][12:]
+	case 64:
+		if len(data[0]) < 24 {
+			return "", fmt.Errorf("encrypted value is too short")
+		}
+		if key, err = hex.DecodeString(keyFile.Key); err != nil {
+			return "", fmt.Errorf("invalid encryption key: %v", err)
+		}
+		if iv, err = hex.DecodeString(data[0][:24]); err != nil {
+			return "", err
+		}
+		data[0] = data[0][24:]
+	default:
+		return "", fmt.Errorf("unsupported encryption key length %d", len(keyFile.Key))
+	}
+
+	ciphertext, err := hex.DecodeString(data[0])
+	if err != nil {
+		return "", err
+	}
+	tag, err := hex.DecodeString(data[1])
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+	plain, err := gcm.Open(nil, iv, append(ciphertext, tag...), nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
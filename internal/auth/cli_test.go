@@ -0,0 +1,50 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"testing"
+)
+
+func TestCliOrg(t *testing.T) {
+	cases := map[string]struct {
+		aliasOrUsername string
+		expected        SfdxAuthUrl
+		err             bool
+	}{
+		"alias with encrypted token": {
+			aliasOrUsername: "dev",
+			expected:        SfdxAuthUrl{ClientId: "PlatformCLI", RefreshToken: "5Aep861cliRefreshToken", InstanceUrl: "https://dev.my.salesforce.com"},
+		},
+		"username with plain token": {
+			aliasOrUsername: "plain@example.com",
+			expected:        SfdxAuthUrl{ClientId: "3MVG9customApp", ClientSecret: "customSecret", RefreshToken: "5Aep861plainRefreshToken", InstanceUrl: "https://plain.sandbox.my.salesforce.com"},
+		},
+		"jwt authorized org": {
+			aliasOrUsername: "jwt",
+			err:             true,
+		},
+		"unknown alias": {
+			aliasOrUsername: "missing",
+			err:             true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			org, err := CliOrg("testdata/sfdx", c.aliasOrUsername)
+			if c.err {
+				if err == nil {
+					t.Fatalf("expected error, got %#v", org)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if org != c.expected {
+				t.Errorf("expected %#v, got %#v", c.expected, org)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/nimajalali/go-force/force"
+)
+
+const (
+	defaultDeviceInterval = 5 * time.Second
+	deviceScope           = "api refresh_token"
+)
+
+// deviceSlowDown is added to the polling interval when Salesforce asks to slow down
+var deviceSlowDown = 5 * time.Second
+
+// DeviceCode is returned when starting the device flow, the user approves the login by
+// entering UserCode at VerificationUri while DeviceCode is used to poll for the token
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationUri string `json:"verification_uri"`
+	Interval        int    `json:"interval"`
+}
+
+// RequestDeviceCode starts the device flow, the connected app must have "Enable for Device Flow" checked
+func RequestDeviceCode(ctx context.Context, client *http.Client, domain string, clientId string) (DeviceCode, error) {
+	var code DeviceCode
+
+	payload := url.Values{}
+	payload.Add("response_type", "device_code")
+	payload.Add("client_id", clientId)
+	payload.Add("scope", deviceScope)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", domain+salesforceOAuthEndpoint, strings.NewReader(payload.Encode()))
+	if err != nil {
+		return code, fmt.Errorf("Error creating device authorization request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return code, fmt.Errorf("Error sending device authorization request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return code, fmt.Errorf("Error reading device authorization response bytes: %v", err)
+	}
+
+	apiError := &force.ApiError{}
+	if err := json.Unmarshal(respBytes, apiError); err == nil {
+		if apiError.Validate() {
+			return code, apiError
+		}
+	}
+
+	if err := json.Unmarshal(respBytes, &code); err != nil {
+		return code, fmt.Errorf("Unable to unmarshal device authorization response: %v", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return code, fmt.Errorf("device authorization response is missing the device or user code")
+	}
+	return code, nil
+}
+
+// AuthenticateDevice polls the token endpoint until the user approved or denied the device code,
+// or the code expired
+func AuthenticateDevice(ctx context.Context, client *http.Client, domain string, clientId string, code DeviceCode) (AuthResponse, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultDeviceInterval
+	}
+
+	payload := url.Values{}
+	payload.Add("grant_type", "device")
+	payload.Add("client_id", clientId)
+	payload.Add("code", code.DeviceCode)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return AuthResponse{}, fmt.Errorf("device authorization was not approved: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+
+		resp, err := requestToken(ctx, client, domain, payload)
+		if apiErr, ok := err.(*force.ApiError); ok {
+			switch apiErr.ErrorName {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += deviceSlowDown
+				continue
+			}
+		}
+		return resp, err
+	}
+}
+
+// deviceLogin runs the device flow, the verification url and user code are only
+// visible in the Terraform log
+func deviceLogin(ctx context.Context, config Config) (AuthResponse, error) {
+	code, err := RequestDeviceCode(ctx, config.HTTPClient, config.LoginUrl, config.ClientId)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("To authorize the Salesforce provider, open %s and enter the code %s", code.VerificationUri, code.UserCode), map[string]interface{}{
+		"verification_uri": code.VerificationUri,
+		"user_code":        code.UserCode,
+	})
+
+	return AuthenticateDevice(ctx, config.HTTPClient, config.LoginUrl, config.ClientId, code)
+}
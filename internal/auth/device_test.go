@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nimajalali/go-force/force"
+)
+
+func TestDeviceLogin(t *testing.T) {
+	defer func(d time.Duration) { deviceSlowDown = d }(deviceSlowDown)
+	deviceSlowDown = 10 * time.Millisecond
+
+	cases := map[string]struct {
+		responses []string
+		err       string
+	}{
+		"approved": {
+			responses: []string{"authorization_pending", "slow_down", "authorization_pending", ""},
+		},
+		"denied": {
+			responses: []string{"authorization_pending", "access_denied"},
+			err:       "access_denied",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var polls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatal(err)
+				}
+				if r.PostForm.Get("client_id") != "id" {
+					t.Errorf("unexpected client_id %q", r.PostForm.Get("client_id"))
+				}
+				if r.PostForm.Get("response_type") == "device_code" {
+					_, _ = w.Write([]byte(`{"device_code":"device","user_code":"ABCD1234","verification_uri":"https://example.my.salesforce.com/setup/connect","interval":1}`))
+					return
+				}
+				if r.PostForm.Get("grant_type") != "device" || r.PostForm.Get("code") != "device" {
+					t.Errorf("unexpected token request %v", r.PostForm)
+				}
+				response := c.responses[atomic.AddInt32(&polls, 1)-1]
+				if response != "" {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"` + response + `","error_description":"` + response + `"}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"access_token":"token","refresh_token":"refresh","instance_url":"https://example.my.salesforce.com"}`))
+			}))
+			defer server.Close()
+
+			config := Config{
+				Credentials: Credentials{AuthType: AuthTypeDevice, ClientId: "id", LoginUrl: server.URL},
+				HTTPClient:  server.Client(),
+			}
+			resp, err := login(context.Background(), config)
+			if c.err != "" {
+				if apiErr, ok := err.(*force.ApiError); !ok || apiErr.ErrorName != c.err {
+					t.Fatalf("expected %s force.ApiError, got %#v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.AccessToken != "token" || resp.RefreshToken != "refresh" {
+				t.Errorf("unexpected response %#v", resp)
+			}
+			if int(polls) != len(c.responses) {
+				t.Errorf("expected %d polls, got %d", len(c.responses), polls)
+			}
+		})
+	}
+}
+
+func TestAuthenticateDevice_canceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"authorization_pending","error_description":"authorization pending"}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	if _, err := AuthenticateDevice(ctx, server.Client(), server.URL, "id", DeviceCode{DeviceCode: "device", Interval: 1}); err == nil {
+		t.Fatal("expected error when the context is canceled")
+	}
+}
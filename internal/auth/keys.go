@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/youmark/pkcs8"
+)
+
+const pemPrefix = "-----BEGIN"
+
+// parsePrivateKey parses an RSA private key in PEM format, the PEM may also be base64 encoded
+// for environments that can't hold newlines. Unencrypted PKCS#1 and PKCS#8 keys, encrypted
+// PKCS#8 keys and legacy encrypted PKCS#1 keys (Proc-Type: 4,ENCRYPTED) are supported.
+func parsePrivateKey(keyBytes []byte, passphrase string) (*rsa.PrivateKey, error) {
+	keyBytes = bytes.TrimSpace(keyBytes)
+	encoding := "PEM"
+	if !bytes.HasPrefix(keyBytes, []byte(pemPrefix)) {
+		decoded, err := decodeBase64(keyBytes)
+		if err != nil || !bytes.HasPrefix(bytes.TrimSpace(decoded), []byte(pemPrefix)) {
+			return nil, fmt.Errorf("private key is neither a PEM encoded key starting with %q, nor a base64 encoded PEM, nor a path to an existing file", pemPrefix+" ...")
+		}
+		keyBytes = bytes.TrimSpace(decoded)
+		encoding = "base64 encoded PEM"
+	}
+
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("not able to parse %s: no valid PEM block found", encoding)
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		//nolint:staticcheck // legacy PEM encryption is insecure but still produced by openssl genrsa -aes256
+		if x509.IsEncryptedPEMBlock(block) {
+			format := fmt.Sprintf("%s legacy encrypted PKCS#1 key (Proc-Type: 4,ENCRYPTED)", encoding)
+			if passphrase == "" {
+				return nil, fmt.Errorf("detected %s but private_key_passphrase is not set", format)
+			}
+			//nolint:staticcheck // see above
+			der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+			if err != nil {
+				return nil, fmt.Errorf("detected %s but decryption failed, the passphrase is likely incorrect: %v", format, err)
+			}
+			key, err := x509.ParsePKCS1PrivateKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("detected %s but the decrypted key is invalid: %v", format, err)
+			}
+			return key, nil
+		}
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("detected %s PKCS#1 key but parsing failed: %v", encoding, err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("detected %s PKCS#8 key but parsing failed: %v", encoding, err)
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("detected %s PKCS#8 key but it is a %T, only RSA keys are supported", encoding, parsed)
+		}
+		return key, nil
+	case "ENCRYPTED PRIVATE KEY":
+		format := fmt.Sprintf("%s encrypted PKCS#8 key", encoding)
+		if passphrase == "" {
+			return nil, fmt.Errorf("detected %s but private_key_passphrase is not set", format)
+		}
+		key, err := pkcs8.ParsePKCS8PrivateKeyRSA(block.Bytes, []byte(passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("detected %s but decryption failed, the passphrase may be incorrect or the key is not RSA: %v", format, err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("detected %s block of type %q, expected one of RSA PRIVATE KEY, PRIVATE KEY or ENCRYPTED PRIVATE KEY", encoding, block.Type)
+	}
+}
+
+func decodeBase64(data []byte) ([]byte, error) {
+	// drop any line breaks introduced by tools like base64 -b 76
+	s := strings.Join(strings.Fields(string(data)), "")
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	return decoded, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/youmark/pkcs8"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pkcs8Der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8Pem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8Der})
+	encryptedDer, err := pkcs8.MarshalPrivateKey(key, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedPkcs8 := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedDer})
+	//nolint:staticcheck // testing support for legacy keys
+	legacyBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte("secret"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := pem.EncodeToMemory(legacyBlock)
+
+	cases := map[string]struct {
+		key        []byte
+		passphrase string
+		err        string
+	}{
+		"pkcs1":                      {key: pkcs1},
+		"pkcs8":                      {key: pkcs8Pem},
+		"base64 pkcs8":               {key: []byte(base64.StdEncoding.EncodeToString(pkcs8Pem))},
+		"encrypted pkcs8":            {key: encryptedPkcs8, passphrase: "secret"},
+		"base64 encrypted pkcs8":     {key: []byte(base64.StdEncoding.EncodeToString(encryptedPkcs8)), passphrase: "secret"},
+		"encrypted pkcs8 no secret":  {key: encryptedPkcs8, err: "encrypted PKCS#8 key but private_key_passphrase is not set"},
+		"encrypted pkcs8 bad secret": {key: encryptedPkcs8, passphrase: "wrong", err: "encrypted PKCS#8 key but decryption failed"},
+		"legacy":                     {key: legacy, passphrase: "secret"},
+		"legacy no secret":           {key: legacy, err: "legacy encrypted PKCS#1 key (Proc-Type: 4,ENCRYPTED) but private_key_passphrase is not set"},
+		"legacy bad secret":          {key: legacy, passphrase: "wrong", err: "legacy encrypted PKCS#1 key (Proc-Type: 4,ENCRYPTED) but"},
+		"garbage":                    {key: []byte("not a key"), err: "neither a PEM encoded key"},
+		"certificate":                {key: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}), err: `block of type "CERTIFICATE"`},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := parsePrivateKey(c.key, c.passphrase)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsed.Equal(key) {
+				t.Error("parsed key does not match")
+			}
+		})
+	}
+}
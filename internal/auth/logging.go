@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// HTTPLogSubsystem is the tflog subsystem of the requests sent to Salesforce, its level
+	// can be set independently with TF_LOG_PROVIDER_SALESFORCE_HTTP
+	HTTPLogSubsystem = "http"
+	httpLogLevelEnv  = "TF_LOG_PROVIDER_SALESFORCE_HTTP"
+	// maxLoggedBodySize truncates the bodies logged at TRACE
+	maxLoggedBodySize = 64 * 1024
+	redacted          = "***"
+)
+
+var (
+	// secret values in form encoded bodies, such as the OAuth token requests
+	formSecretRegexp = regexp.MustCompile(`(^|&)(assertion|client_secret|password|refresh_token|code|access_token)=[^&]*`)
+	// secret values in JSON bodies, such as the OAuth token responses and password changes
+	jsonSecretRegexp = regexp.MustCompile(`"(access_token|refresh_token|id_token|signature|client_secret|device_code|password|NewPassword)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// signed JWTs and Salesforce session ids anywhere in a body
+	jwtRegexp       = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]+`)
+	sessionIdRegexp = regexp.MustCompile(`\b00D\w{12,15}![\w.]+`)
+	// private keys are never sent, masked in case they end up in a body anyway
+	privateKeyRegexp = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// redact masks access tokens, JWT assertions, passwords and private keys
+func redact(body string) string {
+	body = privateKeyRegexp.ReplaceAllString(body, redacted)
+	body = formSecretRegexp.ReplaceAllString(body, "${1}${2}="+redacted)
+	body = jsonSecretRegexp.ReplaceAllString(body, `"${1}"${2}"`+redacted+`"`)
+	body = jwtRegexp.ReplaceAllString(body, redacted)
+	return sessionIdRegexp.ReplaceAllString(body, redacted)
+}
+
+// loggingTransport logs every request to Salesforce in the http subsystem, the method, path,
+// status, duration and API usage at DEBUG and the redacted bodies at TRACE
+type loggingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := tflog.NewSubsystem(req.Context(), HTTPLogSubsystem, tflog.WithLevelFromEnv(httpLogLevelEnv))
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"host":   req.URL.Host,
+		"path":   req.URL.Path,
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	tflog.SubsystemTrace(ctx, HTTPLogSubsystem, "Sending request to Salesforce", map[string]interface{}{
+		"method": req.Method,
+		"path":   req.URL.Path,
+		"query":  redact(req.URL.RawQuery),
+		"body":   loggedBody(reqBody),
+	})
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	fields["duration_ms"] = time.Since(start).Milliseconds()
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(ctx, HTTPLogSubsystem, "Salesforce request failed", fields)
+		return resp, err
+	}
+
+	fields["status"] = resp.StatusCode
+	if limitInfo := resp.Header.Get("Sforce-Limit-Info"); limitInfo != "" {
+		fields["sforce_limit_info"] = limitInfo
+	}
+	tflog.SubsystemDebug(ctx, HTTPLogSubsystem, "Salesforce request completed", fields)
+
+	// the body is read and restored so it can be logged without consuming it
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if readErr != nil {
+		return nil, readErr
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	tflog.SubsystemTrace(ctx, HTTPLogSubsystem, "Received response from Salesforce", map[string]interface{}{
+		"status": resp.StatusCode,
+		"body":   loggedBody(respBody),
+	})
+	return resp, nil
+}
+
+func loggedBody(body []byte) string {
+	if len(body) > maxLoggedBodySize {
+		return redact(string(body[:maxLoggedBodySize])) + "...(truncated)"
+	}
+	return redact(string(body))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// session holds the access token used for REST requests and renews it when
+// Salesforce reports the session as expired or invalid
+type session struct {
+	mu          sync.Mutex
+	accessToken string
+	// login re-runs the configured OAuth flow, nil if the flow can't be repeated. It is called
+	// with the context of the rejected request, the context of Configure ends with that RPC
+	login func(ctx context.Context) (AuthResponse, error)
+}
+
+func (s *session) token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accessToken
+}
+
+// refresh renews the session unless it was already renewed since the expired token
+// was handed out, concurrent callers holding the same expired token share a single login
+func (s *session) refresh(ctx context.Context, expired string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != expired {
+		return s.accessToken, nil
+	}
+	if s.login == nil {
+		return "", fmt.Errorf("the access token has expired and cannot be renewed, please provide a new one")
+	}
+	resp, err := s.login(ctx)
+	if err != nil {
+		return "", fmt.Errorf("the session has expired and re-authentication failed: %w", err)
+	}
+	s.accessToken = resp.AccessToken
+	return s.accessToken, nil
+}
+
+// sessionTransport authorizes requests with the current session token and replays
+// a request once with a renewed token if it was rejected with 401 (INVALID_SESSION_ID)
+type sessionTransport struct {
+	base    http.RoundTripper
+	session *session
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.session.token()
+	resp, err := t.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// the body was already consumed and can't be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	token, err = t.session.refresh(req.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return t.send(req, token)
+}
+
+func (t *sessionTransport) send(req *http.Request, token string) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	clone.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(clone)
+}
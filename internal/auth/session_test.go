@@ -0,0 +1,124 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSessionTransport_refresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var logins int32
+	sess := &session{
+		accessToken: "expired",
+		login: func(context.Context) (AuthResponse, error) {
+			atomic.AddInt32(&logins, 1)
+			return AuthResponse{AccessToken: "fresh"}, nil
+		},
+	}
+	client := &http.Client{Transport: &sessionTransport{base: http.DefaultTransport, session: sess}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequest("POST", server.URL, bytes.NewReader([]byte(`{"Name":"test"}`)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != `{"Name":"test"}` {
+				t.Errorf("unexpected response %d: %s", resp.StatusCode, body)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if logins != 1 {
+		t.Errorf("expected a single login, got %d", logins)
+	}
+}
+
+func TestSessionTransport_accessToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &sessionTransport{base: http.DefaultTransport, session: &session{accessToken: "static"}}}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("expected error when the access token can't be renewed")
+	}
+}
+
+func TestClient_reloginAfterConfigure(t *testing.T) {
+	var logins int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case salesforceOAuthEndpoint:
+			n := atomic.AddInt32(&logins, 1)
+			_, _ = fmt.Fprintf(w, `{"access_token":"token%d","instance_url":"%s","id":"%s/id/00D/005"}`, n, server.URL, server.URL)
+		case salesforceVersionsEndpoint:
+			_, _ = w.Write([]byte(`[{"label":"Summer '22","url":"/services/data/v55.0","version":"55.0"}]`))
+		case "/services/data/v55.0/limits":
+			// the token of the first login has expired
+			if r.Header.Get("Authorization") == "Bearer token1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// the framework cancels the context of Configure once the RPC returns
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := Client(ctx, Config{Credentials: Credentials{
+		AuthType:     AuthTypeClientCredentials,
+		ClientId:     "id",
+		ClientSecret: "secret",
+		LoginUrl:     server.URL,
+	}})
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Do(context.Background(), http.MethodGet, "/services/data/v55.0/limits", nil, nil, nil); err != nil {
+		t.Fatalf("expected the session to be renewed in a later RPC, got %v", err)
+	}
+	if logins != 2 {
+		t.Errorf("expected 2 logins, got %d", logins)
+	}
+}
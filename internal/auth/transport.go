@@ -0,0 +1,101 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+type TransportConfig struct {
+	// HTTPProxy overrides the HTTPS_PROXY/HTTP_PROXY environment variables, credentials
+	// for an authenticating proxy can be passed as userinfo in the url
+	HTTPProxy string
+	// CACertFile is a PEM bundle trusted in addition to the system roots
+	CACertFile string
+	// ClientCert and ClientKey enable mutual TLS, both may be a file path or PEM
+	ClientCert string
+	ClientKey  string
+	// RequestTimeout bounds every request including reading the response body, 0 means no timeout
+	RequestTimeout time.Duration
+}
+
+// NewHTTPClient builds the client shared by the OAuth token exchange and the REST API
+func NewHTTPClient(config TransportConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.HTTPProxy != "" {
+		proxy, err := url.Parse(config.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http_proxy: %v", err)
+		}
+		if proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid http_proxy %q: expected a url such as http://proxy.example.com:3128", proxy.Redacted())
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.CACertFile != "" {
+		path, err := homedir.Expand(config.CACertFile)
+		if err != nil {
+			path = config.CACertFile
+		}
+		caCert, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca_cert_file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("ca_cert_file %s does not contain any PEM encoded certificates", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		certPem, err := readFileOrValue(config.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client_cert: %v", err)
+		}
+		keyPem, err := readFileOrValue(config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client_key: %v", err)
+		}
+		cert, err := tls.X509KeyPair(certPem, keyPem)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client_cert or client_key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// requests are logged whether they are sent, recorded or replayed
+	var base http.RoundTripper = transport
+	cassette, err := cassetteFromEnv(transport)
+	if err != nil {
+		return nil, err
+	}
+	if cassette != nil {
+		base = cassette
+	}
+
+	return &http.Client{
+		Transport: &loggingTransport{base: base},
+		Timeout:   config.RequestTimeout,
+	}, nil
+}
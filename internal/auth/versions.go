@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinimumApiVersion is the oldest API version the provider works with
+	MinimumApiVersion          = "53.0"
+	salesforceVersionsEndpoint = "/services/data"
+)
+
+var apiVersionRegexp = regexp.MustCompile(`^\d+\.\d+$`)
+
+// ApiVersionError is returned when the API versions supported by the org can't be listed or the
+// requested version can't be used
+type ApiVersionError struct {
+	Err error
+}
+
+func (e *ApiVersionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ApiVersionError) Unwrap() error {
+	return e.Err
+}
+
+type apiVersion struct {
+	Label   string `json:"label"`
+	Url     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// supportedApiVersions lists the API versions the org supports, sorted from oldest to newest
+func supportedApiVersions(ctx context.Context, client *http.Client, instanceUrl string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", instanceUrl+salesforceVersionsEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating API versions request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error sending API versions request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading API versions response bytes: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Error listing API versions supported by the org, status %d: %s", resp.StatusCode, respBytes)
+	}
+
+	var available []apiVersion
+	if err := json.Unmarshal(respBytes, &available); err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal API versions response: %v", err)
+	}
+	versions := make([]string, 0, len(available))
+	for _, v := range available {
+		if apiVersionRegexp.MatchString(v.Version) {
+			versions = append(versions, v.Version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareApiVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// resolveApiVersion checks the requested version against the versions supported by the org,
+// the newest supported version is used if none was requested
+func resolveApiVersion(requested string, supported []string) (string, error) {
+	if len(supported) == 0 {
+		return "", fmt.Errorf("the org did not report any supported API versions")
+	}
+	newest := supported[len(supported)-1]
+	if requested == "" {
+		if compareApiVersions(newest, MinimumApiVersion) < 0 {
+			return "", fmt.Errorf("the newest API version supported by the org is %s, the provider requires at least %s", newest, MinimumApiVersion)
+		}
+		return newest, nil
+	}
+
+	requested = strings.TrimPrefix(requested, "v")
+	if !apiVersionRegexp.MatchString(requested) {
+		return "", fmt.Errorf("api_version %q is not in the format MAJOR.MINOR, such as %s", requested, newest)
+	}
+	if compareApiVersions(requested, MinimumApiVersion) < 0 {
+		return "", fmt.Errorf("api_version %s is too old, the provider requires at least %s, the newest version supported by the org is %s", requested, MinimumApiVersion, newest)
+	}
+	for _, v := range supported {
+		if compareApiVersions(v, requested) == 0 {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("api_version %s is not supported by the org, supported versions are %s to %s", requested, supported[0], newest)
+}
+
+// compareApiVersions compares two MAJOR.MINOR versions numerically
+func compareApiVersions(a, b string) int {
+	aMajor, aMinor := splitApiVersion(a)
+	bMajor, bMinor := splitApiVersion(b)
+	if aMajor != bMajor {
+		return aMajor - bMajor
+	}
+	return aMinor - bMinor
+}
+
+func splitApiVersion(v string) (int, int) {
+	parts := strings.SplitN(v, ".", 2)
+	major, _ := strconv.Atoi(parts[0])
+	var minor int
+	if len(parts) == 2 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor
+}
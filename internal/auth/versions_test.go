@@ -0,0 +1,75 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSupportedApiVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != salesforceVersionsEndpoint {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"label":"Winter '22","url":"/services/data/v53.0","version":"53.0"},
+			{"label":"Spring '22","url":"/services/data/v54.0","version":"54.0"},
+			{"label":"Winter '11","url":"/services/data/v20.0","version":"20.0"},
+			{"label":"Summer '22","url":"/services/data/v55.0","version":"55.0"}
+		]`))
+	}))
+	defer server.Close()
+
+	versions, err := supportedApiVersions(context.Background(), server.Client(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"20.0", "53.0", "54.0", "55.0"}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %v, got %v", expected, versions)
+	}
+}
+
+func TestResolveApiVersion(t *testing.T) {
+	supported := []string{"20.0", "52.0", "53.0", "54.0", "55.0"}
+	cases := map[string]struct {
+		requested string
+		expected  string
+		err       string
+	}{
+		"default to newest": {requested: "", expected: "55.0"},
+		"supported":         {requested: "54.0", expected: "54.0"},
+		"leading v":         {requested: "v53.0", expected: "53.0"},
+		"typo":              {requested: "5.30", err: "too old"},
+		"not a version":     {requested: "latest", err: "not in the format MAJOR.MINOR"},
+		"too old":           {requested: "52.0", err: "requires at least 53.0"},
+		"unsupported":       {requested: "56.0", err: "supported versions are 20.0 to 55.0"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			version, err := resolveApiVersion(c.requested, supported)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, version)
+			}
+		})
+	}
+
+	if _, err := resolveApiVersion("", []string{"50.0"}); err == nil {
+		t.Error("expected error when the org doesn't support the minimum version")
+	}
+}
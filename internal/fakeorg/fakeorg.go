@@ -0,0 +1,876 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package fakeorg is an in-memory Salesforce org served over HTTP, it implements the OAuth token
+// endpoint and the parts of the REST API used by the provider so resources can be tested end to
+// end without a real org. Records are kept as maps of fields, there is no schema beyond the
+// SObject types listed in sobjectTypes and a few unique fields
+package fakeorg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// ApiVersion is the newest API version reported by the org
+	ApiVersion = "56.0"
+
+	// Username and Password authenticate the admin user of the org with the password flow, the
+	// other flows issue a token for the admin user without checking any credentials
+	Username = "admin@fakeorg.example.com"
+	Password = "fakeorg-password"
+
+	// dateTimeLayout is the format of the date time fields returned by the REST API
+	dateTimeLayout = "2006-01-02T15:04:05.000-0700"
+)
+
+// supportedApiVersions are the versions listed by /services/data, the oldest is the minimum
+// version of the provider
+var supportedApiVersions = []string{"53.0", "54.0", "55.0", ApiVersion}
+
+// epoch is the date of the first change in the org, every change advances the clock by a second
+// so the timestamps are predictable and If-Unmodified-Since, which has a precision of a second,
+// tells consecutive changes apart
+var epoch = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type sobjectType struct {
+	label      string
+	keyPrefix  string
+	createable bool
+	updateable bool
+	deletable  bool
+	// unique maps fields that must be unique to the error code reported for duplicates
+	unique map[string]string
+}
+
+var sobjectTypes = map[string]sobjectType{
+	"Organization":    {label: "Organization", keyPrefix: "00D", updateable: true},
+	"Profile":         {label: "Profile", keyPrefix: "00e", createable: true, updateable: true, deletable: true},
+	"SetupAuditTrail": {label: "Setup Audit Trail", keyPrefix: "0Ym"},
+	"User": {label: "User", keyPrefix: "005", createable: true, updateable: true,
+		unique: map[string]string{"Username": "DUPLICATE_USERNAME"}},
+	"UserLicense": {label: "User License", keyPrefix: "100"},
+	"UserRole": {label: "Role", keyPrefix: "00E", createable: true, updateable: true, deletable: true,
+		unique: map[string]string{"DeveloperName": "DUPLICATE_DEVELOPER_NAME"}},
+}
+
+// systemFields are set by the org and can't be written through the API
+var systemFields = []string{"Id", "CreatedDate", "CreatedById", "LastModifiedDate", "LastModifiedById", "SystemModstamp"}
+
+var dataPathRegexp = regexp.MustCompile(`^/services/data/v(\d+\.\d+)/(.+)$`)
+
+// Org is a fake org listening on a local HTTP server, it is safe for concurrent use
+type Org struct {
+	server *httptest.Server
+	orgId  string
+	userId string
+
+	mu             sync.Mutex
+	clock          time.Time
+	sequence       int
+	records        map[string]*record
+	tokens         map[string]string
+	faults         []*Fault
+	passwordResets map[string]int
+}
+
+// record is never modified once stored, changes replace the record so a composite request can
+// be rolled back by restoring the previous records
+type record struct {
+	sobject string
+	fields  map[string]interface{}
+}
+
+// New starts a fake org with the admin user, an Organization record, the Salesforce, Salesforce
+// Platform and Chatter Free user licenses and the System Administrator, Standard User and Chatter
+// Free User profiles.
+// The server is closed when the test ends
+func New(t testing.TB) *Org {
+	o := &Org{
+		clock:          epoch,
+		records:        map[string]*record{},
+		tokens:         map[string]string{},
+		passwordResets: map[string]int{},
+	}
+	o.orgId = o.Insert("Organization", map[string]interface{}{"Name": "Fake Org", "OrganizationType": "Developer Edition"})
+	salesforce := o.Insert("UserLicense", map[string]interface{}{"Name": "Salesforce", "LicenseDefinitionKey": "SFDC"})
+	o.Insert("UserLicense", map[string]interface{}{"Name": "Salesforce Platform", "LicenseDefinitionKey": "AUL"})
+	chatter := o.Insert("UserLicense", map[string]interface{}{"Name": "Chatter Free", "LicenseDefinitionKey": "PID_Chatter"})
+	admin := o.Insert("Profile", map[string]interface{}{"Name": "System Administrator", "UserLicenseId": salesforce, "PermissionsModifyAllData": true})
+	o.Insert("Profile", map[string]interface{}{"Name": "Standard User", "UserLicenseId": salesforce, "PermissionsModifyAllData": false})
+	o.Insert("Profile", map[string]interface{}{"Name": "Chatter Free User", "UserLicenseId": chatter, "PermissionsModifyAllData": false})
+	o.userId = o.Insert("User", map[string]interface{}{
+		"Alias":             "admin",
+		"Email":             Username,
+		"EmailEncodingKey":  "UTF-8",
+		"IsActive":          true,
+		"LanguageLocaleKey": "en_US",
+		"LastName":          "Admin",
+		"LocaleSidKey":      "en_US",
+		"Name":              "Fake Admin",
+		"ProfileId":         admin,
+		"TimeZoneSidKey":    "America/New_York",
+		"Username":          Username,
+	})
+
+	o.server = httptest.NewServer(o)
+	t.Cleanup(o.server.Close)
+	return o
+}
+
+// URL is both the login URL and the instance URL of the org
+func (o *Org) URL() string {
+	return o.server.URL
+}
+
+func (o *Org) OrgId() string {
+	return o.orgId
+}
+
+// UserId returns the id of the admin user, which every token is issued for
+func (o *Org) UserId() string {
+	return o.userId
+}
+
+// AccessToken issues a new token, for the access_token auth type
+func (o *Org) AccessToken() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.issueToken()
+}
+
+// ExpireSessions invalidates all tokens issued so far, the next requests are rejected with
+// INVALID_SESSION_ID as when a session times out
+func (o *Org) ExpireSessions() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.tokens = map[string]string{}
+}
+
+// Insert creates a record without going through the API, system fields may be given to override
+// the values set by the org such as a LastModifiedById of another user
+func (o *Org) Insert(sobject string, fields map[string]interface{}) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	typ, ok := sobjectTypes[sobject]
+	if !ok {
+		panic(fmt.Sprintf("fakeorg: unsupported SObject %s", sobject))
+	}
+	id := o.newId(typ.keyPrefix)
+	o.records[shortId(id)] = &record{sobject: sobject, fields: o.stamp(o.userId, id, nil, fields)}
+	return id
+}
+
+// Update changes fields of a record without going through the API, as an administrator would in
+// the user interface. The change is attributed to the admin user unless LastModifiedById is given
+func (o *Org) Update(sobject string, id string, fields map[string]interface{}) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	existing, ok := o.records[shortId(id)]
+	if !ok || existing.sobject != sobject {
+		panic(fmt.Sprintf("fakeorg: no %s %s", sobject, id))
+	}
+	o.records[shortId(id)] = &record{sobject: sobject, fields: o.stamp(o.userId, id, existing.fields, fields)}
+}
+
+// Delete removes a record without going through the API
+func (o *Org) Delete(sobject string, id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if existing, ok := o.records[shortId(id)]; ok && existing.sobject == sobject {
+		delete(o.records, shortId(id))
+	}
+}
+
+// Record returns a copy of the fields of a record, nil if the record doesn't exist
+func (o *Org) Record(sobject string, id string) map[string]interface{} {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	existing, ok := o.records[shortId(id)]
+	if !ok || existing.sobject != sobject {
+		return nil
+	}
+	return copyFields(existing.fields)
+}
+
+// PasswordResets returns how many times the password of the user was reset
+func (o *Org) PasswordResets(userId string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.passwordResets[shortId(userId)]
+}
+
+func (o *Org) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch path := r.URL.Path; {
+	case path == "/services/oauth2/token":
+		o.token(w, r)
+	case path == "/services/data" || path == "/services/data/":
+		o.versions(w)
+	case strings.HasPrefix(path, "/id/"), path == "/services/oauth2/userinfo":
+		if _, ok := o.authorize(w, r); ok {
+			o.identity(w)
+		}
+	case dataPathRegexp.MatchString(path):
+		userId, ok := o.authorize(w, r)
+		if !ok {
+			return
+		}
+		match := dataPathRegexp.FindStringSubmatch(path)
+		o.data(w, r, userId, match[1], strings.Split(strings.Trim(match[2], "/"), "/"))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+// token implements every grant type, only the password grant checks the credentials
+func (o *Org) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed. Allowed are POST")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if r.PostForm.Get("grant_type") == "password" && (r.PostForm.Get("username") != Username || r.PostForm.Get("password") != Password) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "authentication failure"})
+		return
+	}
+
+	o.mu.Lock()
+	token := o.issueToken()
+	o.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": token,
+		"instance_url": o.server.URL,
+		"id":           o.server.URL + "/id/" + o.orgId + "/" + o.userId,
+		"token_type":   "Bearer",
+		"scope":        "api",
+	})
+}
+
+func (o *Org) versions(w http.ResponseWriter) {
+	versions := make([]map[string]string, len(supportedApiVersions))
+	for i, v := range supportedApiVersions {
+		versions[i] = map[string]string{"label": "Fake " + v, "url": "/services/data/v" + v, "version": v}
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// identity answers both the identity URL and the userinfo endpoint
+func (o *Org) identity(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"id":              o.server.URL + "/id/" + o.orgId + "/" + o.userId,
+		"user_id":         o.userId,
+		"organization_id": o.orgId,
+		"username":        Username,
+	})
+}
+
+// authorize checks the bearer token and returns the user it was issued for
+func (o *Org) authorize(w http.ResponseWriter, r *http.Request) (string, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	o.mu.Lock()
+	userId, ok := o.tokens[token]
+	o.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "INVALID_SESSION_ID", "Session expired or invalid")
+	}
+	return userId, ok
+}
+
+func (o *Org) data(w http.ResponseWriter, r *http.Request, userId string, version string, parts []string) {
+	switch {
+	case len(parts) == 1 && parts[0] == "sobjects" && r.Method == http.MethodGet:
+		o.describeGlobal(w, r, version)
+	case len(parts) == 1 && parts[0] == "query" && r.Method == http.MethodGet:
+		o.query(w, r)
+	case len(parts) == 2 && parts[0] == "composite" && parts[1] == "sobjects":
+		o.collection(w, r, userId)
+	case len(parts) == 2 && parts[0] == "sobjects" && r.Method == http.MethodPost:
+		o.insert(w, r, userId, parts[1])
+	case len(parts) == 3 && parts[0] == "sobjects" && parts[2] == "describe" && r.Method == http.MethodGet:
+		o.describe(w, r, version, parts[1])
+	case len(parts) == 3 && parts[0] == "sobjects":
+		o.row(w, r, userId, parts[1], parts[2])
+	case len(parts) == 4 && parts[0] == "sobjects" && parts[1] == "User" && parts[3] == "password" && r.Method == http.MethodDelete:
+		o.resetPassword(w, r, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+func (o *Org) insert(w http.ResponseWriter, r *http.Request, userId string, sobject string) {
+	if o.fail(w, r.Method, sobject) {
+		return
+	}
+	fields, err := decodeFields(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+		return
+	}
+	o.mu.Lock()
+	id, apiErr := o.insertLocked(userId, sobject, fields)
+	o.mu.Unlock()
+	if apiErr != nil {
+		apiErr.write(w)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"id": id, "success": true, "errors": []interface{}{}})
+}
+
+func (o *Org) row(w http.ResponseWriter, r *http.Request, userId string, sobject string, id string) {
+	if o.fail(w, r.Method, sobject) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		o.mu.Lock()
+		existing, ok := o.records[shortId(id)]
+		o.mu.Unlock()
+		if !ok || existing.sobject != sobject {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+			return
+		}
+		var fields []string
+		if param := r.URL.Query().Get("fields"); param != "" {
+			fields = append([]string{"Id"}, strings.Split(param, ",")...)
+		}
+		writeJSON(w, http.StatusOK, o.output(r, existing, fields))
+	case http.MethodPatch:
+		fields, err := decodeFields(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+			return
+		}
+		var since time.Time
+		if header := r.Header.Get("If-Unmodified-Since"); header != "" {
+			if since, err = http.ParseTime(header); err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_HEADER_TYPE", "Invalid If-Unmodified-Since header: "+header)
+				return
+			}
+		}
+		o.mu.Lock()
+		apiErr := o.updateLocked(userId, sobject, id, fields, since)
+		o.mu.Unlock()
+		if apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		o.mu.Lock()
+		apiErr := o.deleteLocked(sobject, id)
+		o.mu.Unlock()
+		if apiErr != nil {
+			apiErr.write(w)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed. Allowed are GET,PATCH,DELETE")
+	}
+}
+
+// collection implements /composite/sobjects, the records succeed or fail on their own unless
+// allOrNone is set, in which case a failure rolls back the records already saved
+func (o *Org) collection(w http.ResponseWriter, r *http.Request, userId string) {
+	var request struct {
+		AllOrNone bool                     `json:"allOrNone"`
+		Records   []map[string]interface{} `json:"records"`
+	}
+	switch r.Method {
+	case http.MethodPost, http.MethodPatch:
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+			return
+		}
+	case http.MethodDelete:
+		request.AllOrNone, _ = strconv.ParseBool(r.URL.Query().Get("allOrNone"))
+		for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			request.Records = append(request.Records, map[string]interface{}{"id": id})
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed. Allowed are POST,PATCH,DELETE")
+		return
+	}
+	if len(request.Records) > 200 {
+		writeError(w, http.StatusBadRequest, "EXCEEDED_ID_LIMIT", "record limit reached. cannot submit more than 200 records into this call")
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	saved := make(map[string]*record, len(o.records))
+	for k, v := range o.records {
+		saved[k] = v
+	}
+
+	results := make([]map[string]interface{}, len(request.Records))
+	failed := false
+	for i, fields := range request.Records {
+		id, _ := fields["id"].(string)
+		delete(fields, "id")
+		sobject := ""
+		if attributes, ok := fields["attributes"].(map[string]interface{}); ok {
+			sobject, _ = attributes["type"].(string)
+		}
+		delete(fields, "attributes")
+		if r.Method == http.MethodDelete {
+			if existing, ok := o.records[shortId(id)]; ok {
+				sobject = existing.sobject
+			}
+		}
+
+		var apiErr *apiError
+		if fault := o.faultLocked(r.Method, sobject); fault != nil {
+			apiErr = &apiError{ErrorCode: fault.ErrorCode, Message: fault.Message}
+		} else {
+			switch r.Method {
+			case http.MethodPost:
+				id, apiErr = o.insertLocked(userId, sobject, fields)
+			case http.MethodPatch:
+				apiErr = o.updateLocked(userId, sobject, id, fields, time.Time{})
+			case http.MethodDelete:
+				apiErr = o.deleteLocked(sobject, id)
+			}
+		}
+		results[i] = map[string]interface{}{"success": apiErr == nil, "errors": []interface{}{}}
+		if id != "" {
+			results[i]["id"] = id
+		}
+		if apiErr != nil {
+			failed = true
+			results[i]["errors"] = []map[string]interface{}{apiErr.collectionError()}
+		}
+	}
+
+	if request.AllOrNone && failed {
+		o.records = saved
+		for _, result := range results {
+			if result["success"] == true {
+				result["success"] = false
+				result["errors"] = []map[string]interface{}{(&apiError{
+					ErrorCode: "ALL_OR_NONE_OPERATION_ROLLED_BACK",
+					Message:   "Record rolled back because not all records were valid and the request was using AllOrNone header",
+				}).collectionError()}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (o *Org) query(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+	if _, ok := sobjectTypes[q.sobject]; !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_TYPE", fmt.Sprintf("sObject type '%s' is not supported.", q.sobject))
+		return
+	}
+	if o.fail(w, r.Method, q.sobject) {
+		return
+	}
+
+	o.mu.Lock()
+	var matches []*record
+	for _, existing := range o.records {
+		if existing.sobject == q.sobject && q.matches(existing.fields) {
+			matches = append(matches, existing)
+		}
+	}
+	o.mu.Unlock()
+
+	q.sort(matches)
+	if q.limit >= 0 && len(matches) > q.limit {
+		matches = matches[:q.limit]
+	}
+	records := make([]map[string]interface{}, len(matches))
+	for i, match := range matches {
+		records[i] = o.output(r, match, q.fields)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"totalSize": len(records), "done": true, "records": records})
+}
+
+func (o *Org) resetPassword(w http.ResponseWriter, r *http.Request, id string) {
+	if o.fail(w, r.Method, "User") {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	existing, ok := o.records[shortId(id)]
+	if !ok || existing.sobject != "User" {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+		return
+	}
+	o.passwordResets[shortId(id)]++
+	writeJSON(w, http.StatusOK, map[string]string{"NewPassword": fmt.Sprintf("fakeorg-reset-%d", o.passwordResets[shortId(id)])})
+}
+
+func (o *Org) describeGlobal(w http.ResponseWriter, r *http.Request, version string) {
+	if o.fail(w, r.Method, "") {
+		return
+	}
+	names := make([]string, 0, len(sobjectTypes))
+	for name := range sobjectTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sobjects := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		sobjects[i] = describeResult(version, name)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"encoding": "UTF-8", "maxBatchSize": 200, "sobjects": sobjects})
+}
+
+// describe lists the system fields and the fields set on any record of the type, their type is
+// guessed from their name and values
+func (o *Org) describe(w http.ResponseWriter, r *http.Request, version string, sobject string) {
+	if _, ok := sobjectTypes[sobject]; !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+		return
+	}
+	if o.fail(w, r.Method, sobject) {
+		return
+	}
+
+	typ := sobjectTypes[sobject]
+	values := map[string]interface{}{}
+	o.mu.Lock()
+	for _, existing := range o.records {
+		if existing.sobject != sobject {
+			continue
+		}
+		for name, value := range existing.fields {
+			if values[name] == nil {
+				values[name] = value
+			}
+		}
+	}
+	o.mu.Unlock()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []map[string]interface{}
+	for _, name := range names {
+		fieldType := "string"
+		switch value := values[name]; {
+		case name == "Id":
+			fieldType = "id"
+		case strings.HasSuffix(name, "Id"):
+			fieldType = "reference"
+		case strings.HasSuffix(name, "Date") || name == "SystemModstamp":
+			fieldType = "datetime"
+		default:
+			switch value.(type) {
+			case bool:
+				fieldType = "boolean"
+			case json.Number:
+				fieldType = "double"
+			}
+		}
+		system := isSystemField(name)
+		fields = append(fields, map[string]interface{}{
+			"name":       name,
+			"label":      name,
+			"type":       fieldType,
+			"nillable":   !system && fieldType != "boolean",
+			"createable": !system && typ.createable,
+			"updateable": !system && typ.updateable,
+		})
+	}
+	result := describeResult(version, sobject)
+	result["fields"] = fields
+	writeJSON(w, http.StatusOK, result)
+}
+
+func describeResult(version string, sobject string) map[string]interface{} {
+	typ := sobjectTypes[sobject]
+	base := fmt.Sprintf("/services/data/v%s/sobjects/%s", version, sobject)
+	return map[string]interface{}{
+		"name":       sobject,
+		"label":      typ.label,
+		"keyPrefix":  typ.keyPrefix,
+		"createable": typ.createable,
+		"updateable": typ.updateable,
+		"deletable":  typ.deletable,
+		"queryable":  true,
+		"urls": map[string]string{
+			"sobject":     base,
+			"describe":    base + "/describe",
+			"rowTemplate": base + "/{ID}",
+		},
+	}
+}
+
+func (o *Org) insertLocked(userId string, sobject string, fields map[string]interface{}) (string, *apiError) {
+	typ, ok := sobjectTypes[sobject]
+	if !ok {
+		return "", &apiError{status: http.StatusNotFound, ErrorCode: "NOT_FOUND", Message: "The requested resource does not exist"}
+	}
+	if !typ.createable {
+		return "", &apiError{status: http.StatusBadRequest, ErrorCode: "INVALID_TYPE_FOR_OPERATION", Message: fmt.Sprintf("entity type %s does not support insert", sobject)}
+	}
+	if apiErr := o.validateLocked(sobject, "", fields); apiErr != nil {
+		return "", apiErr
+	}
+	id := o.newId(typ.keyPrefix)
+	o.records[shortId(id)] = &record{sobject: sobject, fields: o.stamp(userId, id, nil, fields)}
+	return id, nil
+}
+
+func (o *Org) updateLocked(userId string, sobject string, id string, fields map[string]interface{}, since time.Time) *apiError {
+	existing, ok := o.records[shortId(id)]
+	if !ok || existing.sobject != sobject {
+		return &apiError{status: http.StatusNotFound, ErrorCode: "NOT_FOUND", Message: "The requested resource does not exist"}
+	}
+	if !sobjectTypes[sobject].updateable {
+		return &apiError{status: http.StatusBadRequest, ErrorCode: "INVALID_TYPE_FOR_OPERATION", Message: fmt.Sprintf("entity type %s does not support update", sobject)}
+	}
+	if !since.IsZero() {
+		// the header has a precision of a second
+		if modified, err := time.Parse(dateTimeLayout, fmt.Sprint(existing.fields["LastModifiedDate"])); err == nil && modified.Truncate(time.Second).After(since) {
+			return &apiError{status: http.StatusPreconditionFailed, ErrorCode: "PRECONDITION_FAILED", Message: "The requested resource has been modified since " + since.Format(http.TimeFormat)}
+		}
+	}
+	if apiErr := o.validateLocked(sobject, id, fields); apiErr != nil {
+		return apiErr
+	}
+	o.records[shortId(id)] = &record{sobject: sobject, fields: o.stamp(userId, id, existing.fields, fields)}
+	return nil
+}
+
+func (o *Org) deleteLocked(sobject string, id string) *apiError {
+	existing, ok := o.records[shortId(id)]
+	if !ok || existing.sobject != sobject {
+		return &apiError{status: http.StatusNotFound, ErrorCode: "NOT_FOUND", Message: "The requested resource does not exist"}
+	}
+	if !sobjectTypes[sobject].deletable {
+		return &apiError{status: http.StatusMethodNotAllowed, ErrorCode: "METHOD_NOT_ALLOWED", Message: "HTTP Method 'DELETE' not allowed. Allowed are HEAD,GET,PATCH"}
+	}
+	delete(o.records, shortId(id))
+	return nil
+}
+
+// validateLocked rejects writes of system fields and duplicates of unique fields, id is the
+// record being updated and is empty for inserts
+func (o *Org) validateLocked(sobject string, id string, fields map[string]interface{}) *apiError {
+	for name := range fields {
+		if isSystemField(name) {
+			return &apiError{status: http.StatusBadRequest, ErrorCode: "INVALID_FIELD_FOR_INSERT_UPDATE", Message: "Unable to create/update fields: " + name + ". Please check the security settings of this field and verify that it is read/write for your profile or permission set.", Fields: []string{name}}
+		}
+	}
+	for field, code := range sobjectTypes[sobject].unique {
+		value, ok := lookup(fields, field)
+		if !ok || value == nil {
+			continue
+		}
+		for key, existing := range o.records {
+			if existing.sobject != sobject || key == shortId(id) {
+				continue
+			}
+			if other, _ := lookup(existing.fields, field); other == value {
+				return &apiError{status: http.StatusBadRequest, ErrorCode: code, Message: fmt.Sprintf("duplicate value found: %s duplicates value on record with id: %s", field, existing.fields["Id"])}
+			}
+		}
+	}
+	return nil
+}
+
+// stamp returns the fields of a record with the changes applied and the system fields set, the
+// system fields of changes override those set by the org
+func (o *Org) stamp(userId string, id string, existing map[string]interface{}, changes map[string]interface{}) map[string]interface{} {
+	o.clock = o.clock.Add(time.Second)
+	now := o.clock.Format(dateTimeLayout)
+	fields := copyFields(existing)
+	if existing == nil {
+		fields["Id"] = id
+		fields["CreatedDate"] = now
+		fields["CreatedById"] = userId
+	}
+	fields["LastModifiedDate"] = now
+	fields["LastModifiedById"] = userId
+	fields["SystemModstamp"] = now
+	for name, value := range changes {
+		// field names are case insensitive, keep the case of the field as first set
+		if current := fieldName(fields, name); current != "" {
+			name = current
+		}
+		fields[name] = value
+	}
+	return fields
+}
+
+// output returns the fields of a record as returned by the API, with its attributes. All fields
+// are returned when fields is empty, fields that were never set are null
+func (o *Org) output(r *http.Request, existing *record, fields []string) map[string]interface{} {
+	version := ApiVersion
+	if match := dataPathRegexp.FindStringSubmatch(r.URL.Path); match != nil {
+		version = match[1]
+	}
+	out := map[string]interface{}{
+		"attributes": map[string]string{
+			"type": existing.sobject,
+			"url":  fmt.Sprintf("/services/data/v%s/sobjects/%s/%s", version, existing.sobject, existing.fields["Id"]),
+		},
+	}
+	if len(fields) == 0 {
+		for name, value := range existing.fields {
+			out[name] = value
+		}
+		return out
+	}
+	for _, name := range fields {
+		name = strings.TrimSpace(name)
+		if current := fieldName(existing.fields, name); current != "" {
+			name = current
+		}
+		out[name] = existing.fields[name]
+	}
+	return out
+}
+
+func (o *Org) issueToken() string {
+	o.sequence++
+	token := fmt.Sprintf("%s!fakeorg.token.%d", o.orgId[:15], o.sequence)
+	o.tokens[token] = o.userId
+	return token
+}
+
+// newId returns an 18 character id with the key prefix of the SObject type
+func (o *Org) newId(keyPrefix string) string {
+	o.sequence++
+	return longId(fmt.Sprintf("%s5g%010d", keyPrefix, o.sequence))
+}
+
+// longId appends the case checksum to a 15 character id
+func longId(id string) string {
+	suffix := make([]byte, 3)
+	for block := 0; block < 3; block++ {
+		bits := 0
+		for position := 0; position < 5; position++ {
+			if c := id[block*5+position]; c >= 'A' && c <= 'Z' {
+				bits |= 1 << position
+			}
+		}
+		suffix[block] = "ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"[bits]
+	}
+	return id + string(suffix)
+}
+
+// shortId returns the case sensitive 15 character form of an id, which the records are keyed by
+func shortId(id string) string {
+	if len(id) == 18 {
+		return id[:15]
+	}
+	return id
+}
+
+func isSystemField(name string) bool {
+	for _, field := range systemFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldName returns the name of the field of a record matching name regardless of case, an empty
+// string if the record doesn't have the field
+func fieldName(fields map[string]interface{}, name string) string {
+	if _, ok := fields[name]; ok {
+		return name
+	}
+	for field := range fields {
+		if strings.EqualFold(field, name) {
+			return field
+		}
+	}
+	return ""
+}
+
+func lookup(fields map[string]interface{}, name string) (interface{}, bool) {
+	field := fieldName(fields, name)
+	if field == "" {
+		return nil, false
+	}
+	return fields[field], true
+}
+
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		c[name] = value
+	}
+	return c
+}
+
+func decodeFields(r *http.Request) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// apiError is an error in the format of the REST API
+type apiError struct {
+	status    int
+	ErrorCode string   `json:"errorCode"`
+	Message   string   `json:"message"`
+	Fields    []string `json:"fields"`
+}
+
+// collectionError formats the error as in the results of /composite/sobjects
+func (e *apiError) collectionError() map[string]interface{} {
+	fields := e.Fields
+	if fields == nil {
+		fields = []string{}
+	}
+	return map[string]interface{}{"statusCode": e.ErrorCode, "message": e.Message, "fields": fields}
+}
+
+func (e *apiError) write(w http.ResponseWriter) {
+	if e.Fields == nil {
+		e.Fields = []string{}
+	}
+	writeJSON(w, e.status, []*apiError{e})
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	(&apiError{status: status, ErrorCode: code, Message: message}).write(w)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Sforce-Limit-Info", "api-usage=1/15000")
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,259 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakeorg
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/auth"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+type testRole struct {
+	Name          string  `force:",omitempty"`
+	DeveloperName string  `force:",omitempty"`
+	ParentRoleId  *string `force:",omitempty"`
+	LastModified  string  `force:"LastModifiedDate,omitempty"`
+}
+
+func (testRole) ApiName() string {
+	return "UserRole"
+}
+
+func (testRole) ExternalIdApiName() string {
+	return ""
+}
+
+// testClient logs in to the org with the password flow, faults with a Retry-After of 0 are retried
+// without waiting
+func testClient(t *testing.T, org *Org) *rest.Client {
+	t.Helper()
+	client, err := auth.Client(context.Background(), auth.Config{
+		Credentials: auth.Credentials{
+			AuthType:     auth.AuthTypePassword,
+			ClientId:     "fakeorg",
+			ClientSecret: "fakeorg",
+			Username:     Username,
+			Password:     Password,
+			LoginUrl:     org.URL(),
+		},
+		Retry: rest.RetryPolicy{MaxRetries: 2, MaxRetryWait: time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestOrg_login(t *testing.T) {
+	org := New(t)
+	client := testClient(t, org)
+	if client.ApiVersion() != ApiVersion {
+		t.Errorf("expected API version %s, got %s", ApiVersion, client.ApiVersion())
+	}
+	userId, err := client.UserId(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userId != org.UserId() {
+		t.Errorf("expected user %s, got %s", org.UserId(), userId)
+	}
+
+	_, err = auth.Client(context.Background(), auth.Config{Credentials: auth.Credentials{
+		AuthType:     auth.AuthTypePassword,
+		ClientId:     "fakeorg",
+		ClientSecret: "fakeorg",
+		Username:     Username,
+		Password:     "wrong",
+		LoginUrl:     org.URL(),
+	}})
+	if err == nil || !strings.Contains(err.Error(), "authentication failure") {
+		t.Errorf("expected an authentication failure, got %v", err)
+	}
+}
+
+func TestOrg_crud(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+
+	id, err := client.Insert(ctx, testRole{Name: "Engineering", DeveloperName: "Engineering"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(id, "00E") || len(id) != 18 {
+		t.Errorf("unexpected id %s", id)
+	}
+	_, err = client.Insert(ctx, testRole{Name: "Duplicate", DeveloperName: "Engineering"})
+	if apiErr, ok := err.(*rest.Errors); !ok || !apiErr.HasCode("DUPLICATE_DEVELOPER_NAME") {
+		t.Errorf("expected a DUPLICATE_DEVELOPER_NAME error, got %v", err)
+	}
+
+	var role testRole
+	if err := client.Get(ctx, id[:15], nil, &role); err != nil {
+		t.Fatal(err)
+	}
+	if role.Name != "Engineering" {
+		t.Errorf("unexpected role %#v", role)
+	}
+	read, err := time.Parse(dateTimeLayout, role.LastModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.UpdateIfUnmodifiedSince(ctx, id, testRole{Name: "R&D"}, read); err != nil {
+		t.Fatal(err)
+	}
+	err = client.UpdateIfUnmodifiedSince(ctx, id, testRole{Name: "Research"}, read)
+	if !rest.IsPreconditionFailed(err) {
+		t.Errorf("expected the update of a modified record to fail, got %v", err)
+	}
+	if name := org.Record("UserRole", id)["Name"]; name != "R&D" {
+		t.Errorf("expected the role to be renamed R&D, got %v", name)
+	}
+
+	if err := client.Delete(ctx, id, testRole{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Get(ctx, id, nil, &role); !rest.IsNotFound(err) {
+		t.Errorf("expected the role to be deleted, got %v", err)
+	}
+	if err := client.Delete(ctx, org.UserId(), testRole{}); !rest.IsNotFound(err) {
+		t.Errorf("expected the user not to be found as a role, got %v", err)
+	}
+}
+
+func TestOrg_query(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+
+	var profiles struct {
+		rest.BaseQuery
+		Records []struct {
+			Id   string
+			Name string
+		}
+	}
+	if err := client.Query(ctx, rest.BuildQuery("Id, Name", "Profile", []string{"Name = " + rest.QuoteString("standard user")}), &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if profiles.TotalSize != 1 || profiles.Records[0].Name != "Standard User" {
+		t.Errorf("unexpected profiles %#v", profiles)
+	}
+
+	err := client.Query(ctx, "SELECT Id FROM Profile WHERE Name = 'a' OR Name = 'b'", &profiles)
+	if apiErr, ok := err.(*rest.Errors); !ok || !apiErr.HasCode("MALFORMED_QUERY") {
+		t.Errorf("expected unsupported queries to fail, got %v", err)
+	}
+}
+
+func TestOrg_batch(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+	batcher := rest.NewBatcher(client)
+
+	names := []string{"Sales", "Support", "Sales"}
+	ids := make([]string, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			ids[i], errs[i] = batcher.Insert(ctx, testRole{Name: name, DeveloperName: name})
+		}(i, name)
+	}
+	wg.Wait()
+	var inserted, duplicates int
+	for i := range names {
+		if errs[i] == nil {
+			inserted++
+		} else if apiErr, ok := errs[i].(*rest.Errors); ok && apiErr.HasCode("DUPLICATE_DEVELOPER_NAME") {
+			duplicates++
+		} else {
+			t.Errorf("unexpected error %v", errs[i])
+		}
+	}
+	if inserted != 2 || duplicates != 1 {
+		t.Errorf("expected 2 roles to be inserted and 1 duplicate, got %d and %d", inserted, duplicates)
+	}
+
+	var roles [2]testRole
+	for i, id := range ids {
+		if id == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			if err := batcher.Get(ctx, id, &roles[i%2]); err != nil {
+				t.Error(err)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+	if roles[0].Name == "" || roles[1].Name == "" {
+		t.Errorf("expected the roles to be read, got %#v", roles)
+	}
+}
+
+func TestOrg_faults(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+
+	// retried by the client
+	fault := UnableToLockRow(http.MethodPost, "UserRole", 2)
+	fault.RetryAfter = "0"
+	org.Inject(fault)
+	if _, err := client.Insert(ctx, testRole{Name: "Locked", DeveloperName: "Locked"}); err != nil {
+		t.Errorf("expected the insert to succeed once the row is unlocked, got %v", err)
+	}
+
+	fault = RequestLimitExceeded(0)
+	fault.RetryAfter = "0"
+	org.Inject(fault)
+	var role testRole
+	err := client.Get(ctx, org.UserId(), nil, &role)
+	if apiErr, ok := err.(*rest.Errors); !ok || !apiErr.HasCode("REQUEST_LIMIT_EXCEEDED") || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected REQUEST_LIMIT_EXCEEDED, got %v", err)
+	}
+}
+
+func TestOrg_session(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+	org.ExpireSessions()
+
+	// the session transport logs in again
+	if _, err := client.Insert(ctx, testRole{Name: "Renewed", DeveloperName: "Renewed"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrg_resetPassword(t *testing.T) {
+	ctx := context.Background()
+	org := New(t)
+	client := testClient(t, org)
+
+	describe, err := client.Describe(ctx, "User")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := strings.Replace(describe.URLs["rowTemplate"], "{ID}", org.UserId(), 1) + "/password"
+	if err := client.Do(ctx, http.MethodDelete, uri, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if resets := org.PasswordResets(org.UserId()); resets != 1 {
+		t.Errorf("expected 1 password reset, got %d", resets)
+	}
+}
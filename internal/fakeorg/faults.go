@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakeorg
+
+import (
+	"net/http"
+)
+
+// Fault is an error returned instead of processing the matching requests. The records of a
+// /composite/sobjects request fail one by one, with the error in their result, the other
+// requests fail with the status and error code of the fault
+type Fault struct {
+	// Method and SObject select the requests, an empty value matches any. Describes and queries
+	// match the GET method and the SObject they describe or query
+	Method  string
+	SObject string
+
+	StatusCode int
+	ErrorCode  string
+	Message    string
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter string
+	// Times is how many requests fail, every matching request fails when it is 0
+	Times int
+}
+
+// UnableToLockRow fails requests as when the record is locked by another transaction
+func UnableToLockRow(method string, sobject string, times int) Fault {
+	return Fault{
+		Method:     method,
+		SObject:    sobject,
+		StatusCode: http.StatusBadRequest,
+		ErrorCode:  "UNABLE_TO_LOCK_ROW",
+		Message:    "unable to obtain exclusive access to this record or 1 records",
+		Times:      times,
+	}
+}
+
+// RequestLimitExceeded fails any request as when the org exhausted its daily API requests
+func RequestLimitExceeded(times int) Fault {
+	return Fault{
+		StatusCode: http.StatusForbidden,
+		ErrorCode:  "REQUEST_LIMIT_EXCEEDED",
+		Message:    "TotalRequests Limit exceeded.",
+		Times:      times,
+	}
+}
+
+// Inject adds a fault, faults are matched in the order they were added
+func (o *Org) Inject(fault Fault) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.faults = append(o.faults, &fault)
+}
+
+// fail writes the error of the first fault matching the request, it returns false when there is none
+func (o *Org) fail(w http.ResponseWriter, method string, sobject string) bool {
+	o.mu.Lock()
+	fault := o.faultLocked(method, sobject)
+	o.mu.Unlock()
+	if fault == nil {
+		return false
+	}
+	if fault.RetryAfter != "" {
+		w.Header().Set("Retry-After", fault.RetryAfter)
+	}
+	writeError(w, fault.StatusCode, fault.ErrorCode, fault.Message)
+	return true
+}
+
+// faultLocked returns the first fault matching the request and counts it, faults that were used
+// up are removed
+func (o *Org) faultLocked(method string, sobject string) *Fault {
+	for i, fault := range o.faults {
+		if (fault.Method != "" && fault.Method != method) || (fault.SObject != "" && fault.SObject != sobject) {
+			continue
+		}
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				o.faults = append(o.faults[:i:i], o.faults[i+1:]...)
+			}
+		}
+		return fault
+	}
+	return nil
+}
@@ -0,0 +1,382 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakeorg
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// soqlQuery is the subset of SOQL the fake org understands: a list of fields without functions
+// or relationships, conditions joined with AND comparing a field with literals, a single ORDER BY
+// field and a LIMIT
+type soqlQuery struct {
+	fields     []string
+	sobject    string
+	conditions []soqlCondition
+	orderBy    string
+	descending bool
+	limit      int
+}
+
+type soqlCondition struct {
+	field  string
+	op     string
+	values []soqlToken
+}
+
+// soqlToken is a word, a symbol or a string literal, which is unescaped and flagged as quoted
+type soqlToken struct {
+	text   string
+	quoted bool
+}
+
+func (t soqlToken) is(keyword string) bool {
+	return !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+var soqlOperators = map[string]bool{"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func tokenize(query string) ([]soqlToken, error) {
+	var tokens []soqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'':
+			var b strings.Builder
+			i++
+			for ; i < len(query) && query[i] != '\''; i++ {
+				if query[i] == '\\' && i+1 < len(query) {
+					i++
+				}
+				b.WriteByte(query[i])
+			}
+			if i == len(query) {
+				return nil, fmt.Errorf("unterminated string literal in %s", query)
+			}
+			i++
+			tokens = append(tokens, soqlToken{text: b.String(), quoted: true})
+		case c == '(' || c == ')' || c == ',' || c == '=':
+			tokens = append(tokens, soqlToken{text: string(c)})
+			i++
+		case c == '<' || c == '>' || c == '!':
+			if i+1 < len(query) && query[i+1] == '=' {
+				tokens = append(tokens, soqlToken{text: query[i : i+2]})
+				i += 2
+			} else if c == '!' {
+				return nil, fmt.Errorf("unexpected token ! in %s", query)
+			} else {
+				tokens = append(tokens, soqlToken{text: string(c)})
+				i++
+			}
+		default:
+			start := i
+			for i < len(query) && strings.IndexByte(" \t\n\r'(),=<>!", query[i]) < 0 {
+				i++
+			}
+			tokens = append(tokens, soqlToken{text: query[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// parseQuery parses a query, unsupported syntax is reported as an error so tests fail loudly
+// rather than matching the wrong records
+func parseQuery(query string) (*soqlQuery, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	q := &soqlQuery{limit: -1}
+	pos := 0
+	next := func() (soqlToken, bool) {
+		if pos == len(tokens) {
+			return soqlToken{}, false
+		}
+		pos++
+		return tokens[pos-1], true
+	}
+	expect := func(keyword string) error {
+		if t, ok := next(); !ok || !t.is(keyword) {
+			return fmt.Errorf("expected %s in %s", keyword, query)
+		}
+		return nil
+	}
+	word := func(what string) (string, error) {
+		t, ok := next()
+		if !ok || t.quoted || strings.ContainsAny(t.text, "(),=<>!") {
+			return "", fmt.Errorf("expected %s in %s", what, query)
+		}
+		return t.text, nil
+	}
+
+	if err := expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		field, err := word("a field")
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(field, ".") {
+			return nil, fmt.Errorf("relationship fields such as %s are not supported by the fake org", field)
+		}
+		q.fields = append(q.fields, field)
+		t, ok := next()
+		if !ok {
+			return nil, fmt.Errorf("expected FROM in %s", query)
+		}
+		if t.is("FROM") {
+			break
+		}
+		if t.text != "," || t.quoted {
+			return nil, fmt.Errorf("unexpected %s in the fields of %s", t.text, query)
+		}
+	}
+	if q.sobject, err = word("an SObject"); err != nil {
+		return nil, err
+	}
+
+	t, ok := next()
+	if ok && t.is("WHERE") {
+		for {
+			condition, err := parseCondition(next, word, query)
+			if err != nil {
+				return nil, err
+			}
+			q.conditions = append(q.conditions, condition)
+			if t, ok = next(); !ok || !t.is("AND") {
+				break
+			}
+		}
+	}
+	if ok && t.is("ORDER") {
+		if err := expect("BY"); err != nil {
+			return nil, err
+		}
+		if q.orderBy, err = word("a field"); err != nil {
+			return nil, err
+		}
+		t, ok = next()
+		if ok && (t.is("ASC") || t.is("DESC")) {
+			q.descending = t.is("DESC")
+			t, ok = next()
+		}
+	}
+	if ok && t.is("LIMIT") {
+		limit, err := word("a limit")
+		if err != nil {
+			return nil, err
+		}
+		if q.limit, err = strconv.Atoi(limit); err != nil || q.limit < 0 {
+			return nil, fmt.Errorf("invalid LIMIT %s in %s", limit, query)
+		}
+		t, ok = next()
+	}
+	if ok {
+		return nil, fmt.Errorf("unexpected %s in %s, the fake org only supports conditions joined with AND, ORDER BY and LIMIT", t.text, query)
+	}
+	return q, nil
+}
+
+func parseCondition(next func() (soqlToken, bool), word func(string) (string, error), query string) (soqlCondition, error) {
+	field, err := word("a field")
+	if err != nil {
+		return soqlCondition{}, err
+	}
+	condition := soqlCondition{field: field}
+	op, ok := next()
+	switch {
+	case ok && op.is("IN"):
+		condition.op = "IN"
+		if t, ok := next(); !ok || t.text != "(" || t.quoted {
+			return soqlCondition{}, fmt.Errorf("expected ( after IN in %s", query)
+		}
+		for {
+			value, ok := next()
+			if !ok {
+				return soqlCondition{}, fmt.Errorf("unterminated IN in %s", query)
+			}
+			condition.values = append(condition.values, value)
+			t, ok := next()
+			if ok && t.text == ")" && !t.quoted {
+				break
+			}
+			if !ok || t.text != "," || t.quoted {
+				return soqlCondition{}, fmt.Errorf("expected , or ) in the IN of %s", query)
+			}
+		}
+	case ok && !op.quoted && soqlOperators[op.text]:
+		condition.op = op.text
+		value, ok := next()
+		if !ok {
+			return soqlCondition{}, fmt.Errorf("expected a value after %s %s in %s", field, op.text, query)
+		}
+		condition.values = []soqlToken{value}
+	default:
+		return soqlCondition{}, fmt.Errorf("expected an operator after %s in %s, the fake org supports =, !=, <, <=, >, >= and IN", field, query)
+	}
+	return condition, nil
+}
+
+func (q *soqlQuery) matches(fields map[string]interface{}) bool {
+	for _, condition := range q.conditions {
+		value, _ := lookup(fields, condition.field)
+		isId := strings.HasSuffix(strings.ToLower(condition.field), "id")
+		switch condition.op {
+		case "IN", "=":
+			found := false
+			for _, literal := range condition.values {
+				if c, ok := compare(value, literal, isId); ok && c == 0 {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		case "!=":
+			if c, ok := compare(value, condition.values[0], isId); ok && c == 0 {
+				return false
+			}
+		default:
+			c, ok := compare(value, condition.values[0], isId)
+			if !ok || value == nil {
+				return false
+			}
+			if (condition.op == "<" && c >= 0) || (condition.op == "<=" && c > 0) ||
+				(condition.op == ">" && c <= 0) || (condition.op == ">=" && c < 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sort orders the records by the ORDER BY field, or by id for a stable order, nulls come first
+func (q *soqlQuery) sort(records []*record) {
+	field := q.orderBy
+	if field == "" {
+		field = "Id"
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, _ := lookup(records[i].fields, field)
+		b, _ := lookup(records[j].fields, field)
+		c := compareValues(a, b)
+		if q.descending {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// compare compares a field value with a literal, it returns false when they can't be compared.
+// Strings compare regardless of case as in Salesforce, except ids which are case sensitive and
+// match in both their 15 and 18 character forms
+func compare(value interface{}, literal soqlToken, isId bool) (int, bool) {
+	if literal.is("null") {
+		if value == nil {
+			return 0, true
+		}
+		return 1, true
+	}
+	if value == nil {
+		return -1, true
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if literal.quoted || !(literal.is("true") || literal.is("false")) {
+			return 0, false
+		}
+		if v == literal.is("true") {
+			return 0, true
+		}
+		return 1, true
+	case json.Number, int, float64:
+		n, err := strconv.ParseFloat(literal.text, 64)
+		if err != nil || literal.quoted {
+			return 0, false
+		}
+		return compareValues(value, n), true
+	case string:
+		if t, err := time.Parse(dateTimeLayout, v); err == nil && !literal.quoted {
+			if lt, ok := parseDateTimeLiteral(literal.text); ok {
+				return compareTimes(t, lt), true
+			}
+			return 0, false
+		}
+		if !literal.quoted {
+			return 0, false
+		}
+		if isId && (len(v) == 15 || len(v) == 18) && (len(literal.text) == 15 || len(literal.text) == 18) {
+			return strings.Compare(shortId(v), shortId(literal.text)), true
+		}
+		return strings.Compare(strings.ToLower(v), strings.ToLower(literal.text)), true
+	}
+	return 0, false
+}
+
+// compareValues orders two field values, nulls first
+func compareValues(a interface{}, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if fa, ok := number(a); ok {
+		if fb, ok := number(b); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	// the date time format of the API sorts chronologically as long as the offsets are equal
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// parseDateTimeLiteral parses the date time literals of SOQL, such as 2022-01-01T00:00:00Z
+func parseDateTimeLiteral(literal string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05.000Z07:00", "2006-01-02T15:04:05-0700", "2006-01-02T15:04:05.000-0700"} {
+		if t, err := time.Parse(layout, literal); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package fakeorg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	fields := map[string]interface{}{
+		"Id":               "00E5g0000000001AAA",
+		"Name":             "O'Brien",
+		"IsActive":         true,
+		"ParentRoleId":     nil,
+		"NumberOfSeats":    json.Number("10"),
+		"LastModifiedDate": "2022-06-01T12:34:56.000+0000",
+	}
+	for query, matches := range map[string]bool{
+		"SELECT Id FROM UserRole":                                                          true,
+		"select id from UserRole where name = 'o\\'brien'":                                 true,
+		"SELECT Id FROM UserRole WHERE Id = '00E5g0000000001'":                             true,
+		"SELECT Id FROM UserRole WHERE Id IN ('00E5g0000000002AAA', '00E5g0000000001AAA')": true,
+		"SELECT Id FROM UserRole WHERE Id = '00e5g0000000001AAA'":                          false,
+		"SELECT Id FROM UserRole WHERE IsActive = true AND ParentRoleId = null":            true,
+		"SELECT Id FROM UserRole WHERE IsActive = false":                                   false,
+		"SELECT Id FROM UserRole WHERE ParentRoleId != null":                               false,
+		"SELECT Id FROM UserRole WHERE NumberOfSeats >= 10 AND NumberOfSeats < 11":         true,
+		"SELECT Id FROM UserRole WHERE LastModifiedDate >= 2022-06-01T12:34:56Z AND LastModifiedDate <= 2022-06-01T12:35:56Z ORDER BY LastModifiedDate DESC LIMIT 10": true,
+		"SELECT Id FROM UserRole WHERE LastModifiedDate > 2022-06-01T12:34:56Z":                                                                                       false,
+	} {
+		q, err := parseQuery(query)
+		if err != nil {
+			t.Errorf("%s: %v", query, err)
+			continue
+		}
+		if q.sobject != "UserRole" || len(q.fields) != 1 {
+			t.Errorf("%s: unexpected query %#v", query, q)
+		}
+		if q.matches(fields) != matches {
+			t.Errorf("%s: expected match %t", query, matches)
+		}
+	}
+
+	for _, query := range []string{
+		"SELECT Id FROM UserRole WHERE Name = 'a' OR Name = 'b'",
+		"SELECT Id, Parent.Name FROM UserRole",
+		"SELECT COUNT() FROM UserRole",
+		"SELECT Id FROM UserRole WHERE Name LIKE 'a%'",
+		"SELECT Id FROM UserRole WHERE Name = 'unterminated",
+		"SELECT Id FROM UserRole LIMIT many",
+	} {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("expected %s to be rejected", query)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/auth"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+const (
+	// cassetteInstanceUrl is the instance the requests of the cassettes are sent to, whatever the
+	// instance they were recorded against
+	cassetteInstanceUrl = "https://example.my.salesforce.com"
+	cassetteApiVersion  = "56.0"
+)
+
+// replayClient returns a client answering from a cassette of testdata/cassettes. Run the tests
+// with SALESFORCE_CASSETTE_MODE=record and the SALESFORCE_INSTANCE_URL and SALESFORCE_ACCESS_TOKEN
+// of an org to record them again once the requests of the provider changed. The tests create the
+// records they read and delete them afterwards, the cassettes hold the whole exchange
+func replayClient(t *testing.T, cassette string) *rest.Client {
+	t.Helper()
+	mode := auth.CassetteModeReplay
+	var base http.RoundTripper
+	if os.Getenv(auth.CassetteModeEnv) == auth.CassetteModeRecord {
+		instanceUrl, token := os.Getenv("SALESFORCE_INSTANCE_URL"), os.Getenv("SALESFORCE_ACCESS_TOKEN")
+		if instanceUrl == "" || token == "" {
+			t.Fatal("SALESFORCE_INSTANCE_URL and SALESFORCE_ACCESS_TOKEN must be set to record cassettes")
+		}
+		target, err := url.Parse(instanceUrl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mode = auth.CassetteModeRecord
+		base = &instanceTransport{target: target, token: token}
+	}
+	transport, err := auth.NewCassetteTransport(mode, filepath.Join("testdata", "cassettes", cassette), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rest.NewClient(&http.Client{Transport: transport}, cassetteInstanceUrl, cassetteApiVersion, rest.RetryPolicy{})
+}
+
+// instanceTransport sends the requests addressed to the cassette instance to the org being
+// recorded, and replaces the address of the org in the responses, such as in identity URLs
+type instanceTransport struct {
+	target *url.URL
+	token  string
+}
+
+func (t *instanceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = ""
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.ReplaceAll(body, []byte(strings.TrimSuffix(t.target.String(), "/")), []byte(cassetteInstanceUrl))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Del("Content-Length")
+	return resp, nil
+}
+
+// testInsert creates a record for the test and deletes it once the test is done
+func testInsert(t *testing.T, client *rest.Client, sobject rest.SObject) string {
+	t.Helper()
+	id, err := client.Insert(context.Background(), sobject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := client.Delete(context.Background(), id, sobject); err != nil && !isNotFoundError(err) {
+			t.Errorf("unable to delete %s %s: %v", sobject.ApiName(), id, err)
+		}
+	})
+	return id
+}
+
+func TestResource_replayRefresh(t *testing.T) {
+	ctx := context.Background()
+	schema, diags := userRoleType{}.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	client := replayClient(t, "user_role_refresh.json")
+	parent := testInsert(t, client, userRoleResourceData{Name: "Executives", DeveloperName: "tf_replay_executives"})
+	engineering := testInsert(t, client, userRoleResourceData{Name: "Engineering", DeveloperName: "tf_replay_engineering", ParentRoleId: &parent})
+	deleted := testInsert(t, client, userRoleResourceData{Name: "Sales", DeveloperName: "tf_replay_sales"})
+	if err := client.Delete(ctx, deleted, userRoleResourceData{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the reads of a refresh are coalesced into a single query
+	batch := rest.NewBatcher(client)
+	ids := []string{engineering, parent, deleted}
+	responses := make([]*tfsdk.ReadResourceResponse, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		r := &userRoleResource{Resource: Resource{
+			Client: client,
+			Batch:  batch,
+			Data:   &userRoleResourceData{},
+		}}
+		state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+			"id":             tftypes.NewValue(tftypes.String, id),
+			"name":           tftypes.NewValue(tftypes.String, "Renamed"),
+			"developer_name": tftypes.NewValue(tftypes.String, "Renamed"),
+		})}
+		responses[i] = &tfsdk.ReadResourceResponse{State: state}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Read(ctx, tfsdk.ReadResourceRequest{State: state}, responses[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, resp := range responses {
+		if resp.Diagnostics.HasError() {
+			t.Fatal(resp.Diagnostics)
+		}
+		if ids[i] == deleted {
+			if !resp.State.Raw.IsNull() {
+				t.Errorf("expected the deleted role %s to be removed from state", deleted)
+			}
+			continue
+		}
+		var data userRoleResourceData
+		if diags := resp.State.Get(ctx, &data); diags.HasError() {
+			t.Fatal(diags)
+		}
+		if data.Name == "Renamed" || data.LastModifiedDate.Value == "" {
+			t.Errorf("expected the role to be read from the org, got %#v", data)
+		}
+		if ids[i] == engineering && (data.ParentRoleId == nil || *data.ParentRoleId != parent) {
+			t.Errorf("expected the parent role %s, got %#v", parent, data.ParentRoleId)
+		}
+	}
+}
+
+func TestResourceProfile_replayRefresh(t *testing.T) {
+	ctx := context.Background()
+	schema, diags := profileType{}.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	client := replayClient(t, "profile_refresh.json")
+	var licenses userLicenseQueryResponse
+	if err := client.Query(ctx, rest.BuildQuery("Id, LicenseDefinitionKey", "UserLicense", []string{"LicenseDefinitionKey = 'SFDC'"}), &licenses); err != nil {
+		t.Fatal(err)
+	}
+	if len(licenses.Records) == 0 || licenses.Records[0].Id == nil {
+		t.Fatal("the org has no Salesforce user license")
+	}
+	license := *licenses.Records[0].Id
+	id := testInsert(t, client, profileMap{
+		"Name":                     "tf-replay-auditors",
+		"Description":              "Read only access",
+		"UserLicenseId":            license,
+		"PermissionsModifyAllData": false,
+		"PermissionsViewAllData":   true,
+	})
+
+	r := &profileResource{client: client, batch: rest.NewBatcher(client)}
+	state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, id),
+		"name":            tftypes.NewValue(tftypes.String, "tf-replay-auditors"),
+		"user_license_id": tftypes.NewValue(tftypes.String, license),
+		"permissions": tftypes.NewValue(tftypes.Map{ElementType: tftypes.Bool}, map[string]tftypes.Value{
+			"ModifyAllData": tftypes.NewValue(tftypes.Bool, true),
+			"ViewAllData":   tftypes.NewValue(tftypes.Bool, true),
+		}),
+	})}
+	resp := &tfsdk.ReadResourceResponse{State: state}
+	r.Read(ctx, tfsdk.ReadResourceRequest{State: state}, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+
+	var data profileResourceData
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	expected := map[string]attr.Value{"ModifyAllData": types.Bool{Value: false}, "ViewAllData": types.Bool{Value: true}}
+	if data.Description == nil || *data.Description != "Read only access" || !data.Permissions.Equal(types.Map{ElemType: types.BoolType, Elems: expected}) {
+		t.Errorf("unexpected profile %#v", data)
+	}
+}
+
+func TestDataSources_replay(t *testing.T) {
+	ctx := context.Background()
+	client := replayClient(t, "data_sources.json")
+
+	profileSchema, diags := profileDatasourceType{}.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	config := tfsdk.Config{Schema: profileSchema, Raw: testObject(t, profileSchema, map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "Standard User"),
+	})}
+	profileResp := &tfsdk.ReadDataSourceResponse{State: tfsdk.State{Schema: profileSchema, Raw: config.Raw}}
+	profileDataSource{client: client}.Read(ctx, tfsdk.ReadDataSourceRequest{Config: config}, profileResp)
+	if profileResp.Diagnostics.HasError() {
+		t.Fatal(profileResp.Diagnostics)
+	}
+	var profile profileData
+	if diags := profileResp.State.Get(ctx, &profile); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if profile.Id == nil || !strings.HasPrefix(*profile.Id, "00e") {
+		t.Errorf("expected the id of the Standard User profile, got %#v", profile)
+	}
+
+	licenseSchema, diags := userLicenseDatasourceType{}.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	config = tfsdk.Config{Schema: licenseSchema, Raw: testObject(t, licenseSchema, map[string]tftypes.Value{
+		"license_definition_key": tftypes.NewValue(tftypes.String, "PID_Chatter"),
+	})}
+	licenseResp := &tfsdk.ReadDataSourceResponse{State: tfsdk.State{Schema: licenseSchema, Raw: config.Raw}}
+	userLicenceDataSource{client: client}.Read(ctx, tfsdk.ReadDataSourceRequest{Config: config}, licenseResp)
+	if licenseResp.Diagnostics.HasError() {
+		t.Fatal(licenseResp.Diagnostics)
+	}
+	var license userLicenseData
+	if diags := licenseResp.State.Get(ctx, &license); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if license.Id == nil || !strings.HasPrefix(*license.Id, "100") {
+		t.Errorf("expected the id of the Chatter Free license, got %#v", license)
+	}
+}
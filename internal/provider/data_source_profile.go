@@ -10,8 +10,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/nimajalali/go-force/force"
-	"github.com/nimajalali/go-force/sobjects"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 type profileDatasourceType struct {
@@ -47,7 +46,7 @@ func (p profileDatasourceType) NewDataSource(_ context.Context, prov tfsdk.Provi
 }
 
 type profileDataSource struct {
-	client *force.ForceApi
+	client *rest.Client
 }
 
 type profileData struct {
@@ -56,7 +55,7 @@ type profileData struct {
 }
 
 type profileQueryResponse struct {
-	sobjects.BaseQuery
+	rest.BaseQuery
 	Records []profileData
 }
 
@@ -68,9 +67,9 @@ func (p profileDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourceReq
 	}
 
 	var query profileQueryResponse
-	nameFilter := fmt.Sprintf("Name = '%s'", pData.Name)
-	if err := p.client.Query(force.BuildQuery("Id, Name", "Profile", []string{nameFilter}), &query); err != nil {
-		resp.Diagnostics.AddError("Error Getting Profile", err.Error())
+	nameFilter := "Name = " + rest.QuoteString(pData.Name)
+	if err := p.client.Query(ctx, rest.BuildQuery("Id, Name", "Profile", []string{nameFilter}), &query); err != nil {
+		addApiError(&resp.Diagnostics, "Error Getting Profile", err, nil)
 		return
 	}
 	if len(query.Records) == 0 {
@@ -4,10 +4,13 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 func TestAccDataSourceProfile_basic(t *testing.T) {
@@ -27,6 +30,51 @@ func TestAccDataSourceProfile_basic(t *testing.T) {
 	})
 }
 
+func TestUnitDataSourceProfile_basic(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccDataSourceProfile_basic("Chatter Free User"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.salesforce_profile.test", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceProfile_fakeOrg(t *testing.T) {
+	ctx := context.Background()
+	org := fakeorg.New(t)
+	read := testFakeOrgDataSource(t, org, profileDatasourceType{})
+
+	resp := read(map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "Chatter Free User"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	var data profileData
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Id == nil || org.Record("Profile", *data.Id)["Name"] != "Chatter Free User" {
+		t.Errorf("expected the Chatter Free User profile, got %#v", data)
+	}
+
+	resp = read(map[string]tftypes.Value{
+		"name": tftypes.NewValue(tftypes.String, "Missing"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error reading a profile that does not exist")
+	}
+}
+
 func testAccDataSourceProfile_basic(name string) string {
 	return fmt.Sprintf(`
 data "salesforce_profile" "test" {
@@ -4,10 +4,13 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 func TestAccDataSourceUserLicense_basic(t *testing.T) {
@@ -27,6 +30,51 @@ func TestAccDataSourceUserLicense_basic(t *testing.T) {
 	})
 }
 
+func TestUnitDataSourceUserLicense_basic(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccDataSourceUserLicense_basic("AUL"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet("data.salesforce_user_license.test", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestDataSourceUserLicense_fakeOrg(t *testing.T) {
+	ctx := context.Background()
+	org := fakeorg.New(t)
+	read := testFakeOrgDataSource(t, org, userLicenseDatasourceType{})
+
+	resp := read(map[string]tftypes.Value{
+		"license_definition_key": tftypes.NewValue(tftypes.String, "AUL"),
+	})
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+	var data userLicenseData
+	if diags := resp.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.Id == nil || org.Record("UserLicense", *data.Id)["Name"] != "Salesforce Platform" {
+		t.Errorf("expected the Salesforce Platform license, got %#v", data)
+	}
+
+	resp = read(map[string]tftypes.Value{
+		"license_definition_key": tftypes.NewValue(tftypes.String, "Missing"),
+	})
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error reading a license that does not exist")
+	}
+}
+
 func testAccDataSourceUserLicense_basic(name string) string {
 	return fmt.Sprintf(`
 data "salesforce_user_license" "test" {
@@ -11,8 +11,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-provider-salesforce/internal/picklists"
-	"github.com/nimajalali/go-force/force"
-	"github.com/nimajalali/go-force/sobjects"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 type userLicenseDatasourceType struct {
@@ -48,7 +47,7 @@ func (u userLicenseDatasourceType) NewDataSource(_ context.Context, prov tfsdk.P
 }
 
 type userLicenceDataSource struct {
-	client *force.ForceApi
+	client *rest.Client
 }
 
 type userLicenseData struct {
@@ -57,7 +56,7 @@ type userLicenseData struct {
 }
 
 type userLicenseQueryResponse struct {
-	sobjects.BaseQuery
+	rest.BaseQuery
 	Records []userLicenseData
 }
 
@@ -69,9 +68,9 @@ func (u userLicenceDataSource) Read(ctx context.Context, req tfsdk.ReadDataSourc
 	}
 
 	var query userLicenseQueryResponse
-	licenseDefinitionKeyFilter := fmt.Sprintf("LicenseDefinitionKey = '%s'", uData.LicenseDefinitionKey)
-	if err := u.client.Query(force.BuildQuery("Id, LicenseDefinitionKey", "UserLicense", []string{licenseDefinitionKeyFilter}), &query); err != nil {
-		resp.Diagnostics.AddError("Error Getting User License", err.Error())
+	licenseDefinitionKeyFilter := "LicenseDefinitionKey = " + rest.QuoteString(uData.LicenseDefinitionKey)
+	if err := u.client.Query(ctx, rest.BuildQuery("Id, LicenseDefinitionKey", "UserLicense", []string{licenseDefinitionKeyFilter}), &query); err != nil {
+		addApiError(&resp.Diagnostics, "Error Getting User License", err, nil)
 		return
 	}
 	if len(query.Records) == 0 {
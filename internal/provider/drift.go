@@ -0,0 +1,150 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+// auditTrailWindow widens the period searched in the Setup Audit Trail, its entries aren't
+// recorded at exactly the LastModifiedDate of the record
+const auditTrailWindow = time.Minute
+
+// auditTrailLimit is the maximum number of Setup Audit Trail entries included in a warning
+const auditTrailLimit = 10
+
+type setupAuditTrailEntry struct {
+	CreatedDate string
+	Section     string
+	Display     string
+}
+
+// driftWarning attributes the differences between the state and the record read from Salesforce
+// to the user who last modified the record, there is nothing to report when that is the user the
+// provider authenticates as since the change was then applied by Terraform
+func (r *Resource) driftWarning(ctx context.Context, before tfsdk.State, after tfsdk.State) diag.Diagnostics {
+	versioned, ok := r.Data.(VersionedData)
+	if !ok || versioned.GetLastModifiedById() == "" || !stateDiffers(before.Raw, after.Raw) {
+		return nil
+	}
+	sobject := r.Data.Instance().ApiName()
+	modifiedBy := versioned.GetLastModifiedById()
+
+	self, err := r.Client.UserId(ctx)
+	if err != nil {
+		tflog.Warn(ctx, "Unable to identify the authenticated user, changes made outside of Terraform can't be attributed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	if sameId(self, modifiedBy) {
+		return nil
+	}
+
+	modifiedAt, err := time.Parse(lastModifiedDateLayout, versioned.GetLastModifiedDate())
+	if err != nil {
+		return nil
+	}
+	// search the audit trail since the record was last read, or around the modification
+	since := modifiedAt.Add(-auditTrailWindow)
+	var previous types.String
+	if diags := before.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("last_modified_date"), &previous); !diags.HasError() && previous.Value != "" {
+		if t, err := time.Parse(lastModifiedDateLayout, previous.Value); err == nil && t.Before(since) {
+			since = t
+		}
+	}
+
+	detail := fmt.Sprintf("The %s %s differs from the Terraform state, it was last modified at %s by %s, not by the user the provider authenticates as.",
+		sobject, r.Data.GetId(), modifiedAt.UTC().Format(time.RFC3339), r.userName(ctx, modifiedBy))
+	entries, err := r.setupAuditTrail(ctx, modifiedBy, since, modifiedAt.Add(auditTrailWindow))
+	if err != nil {
+		// reading the audit trail requires the View Setup and Configuration permission
+		tflog.Debug(ctx, "Unable to read the Setup Audit Trail", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if len(entries) > 0 {
+		detail += "\n\nSetup Audit Trail entries of that user:"
+		for _, entry := range entries {
+			detail += fmt.Sprintf("\n- %s %s: %s", entry.CreatedDate, entry.Section, entry.Display)
+		}
+	}
+
+	var diags diag.Diagnostics
+	diags.AddWarning(fmt.Sprintf("%s modified outside of Terraform", sobject), detail)
+	return diags
+}
+
+// userName describes a user by name and username, falling back to the ID when the user can't be read
+func (r *Resource) userName(ctx context.Context, id string) string {
+	var resp struct {
+		rest.BaseQuery
+		Records []struct {
+			Name     string
+			Username string
+		}
+	}
+	query := rest.BuildQuery("Name, Username", "User", []string{"Id = " + rest.QuoteString(id)})
+	if err := r.Client.Query(ctx, query, &resp); err != nil || len(resp.Records) == 0 {
+		return id
+	}
+	return fmt.Sprintf("%s (%s, %s)", resp.Records[0].Name, resp.Records[0].Username, id)
+}
+
+// setupAuditTrail returns the most recent changes made in Setup by the user during the period
+func (r *Resource) setupAuditTrail(ctx context.Context, userId string, from time.Time, to time.Time) ([]setupAuditTrailEntry, error) {
+	var resp struct {
+		rest.BaseQuery
+		Records []setupAuditTrailEntry
+	}
+	query := rest.BuildQuery("CreatedDate, Section, Display", "SetupAuditTrail", []string{
+		"CreatedById = " + rest.QuoteString(userId),
+		"CreatedDate >= " + soqlDateTime(from),
+		"CreatedDate <= " + soqlDateTime(to),
+	}) + fmt.Sprintf(" ORDER BY CreatedDate DESC LIMIT %d", auditTrailLimit)
+	if err := r.Client.Query(ctx, query, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Records, nil
+}
+
+// stateDiffers returns true if any attribute other than last_modified_date differs
+func stateDiffers(before tftypes.Value, after tftypes.Value) bool {
+	diffs, err := before.Diff(after)
+	if err != nil {
+		return false
+	}
+	for _, d := range diffs {
+		steps := d.Path.Steps()
+		if len(steps) == 0 {
+			continue
+		}
+		if name, ok := steps[0].(tftypes.AttributeName); ok && name == "last_modified_date" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// sameId compares ids regardless of whether they are in the 15 or the 18 character form
+func sameId(a string, b string) bool {
+	if len(a) >= 15 && len(b) >= 15 {
+		return a[:15] == b[:15]
+	}
+	return a == b
+}
+
+func soqlDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
@@ -0,0 +1,103 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+func TestResource_driftWarning(t *testing.T) {
+	const self = "005000000000001AAA"
+	const admin = "005000000000002AAA"
+	for name, tc := range map[string]struct {
+		name       string
+		modifiedBy string
+		warning    bool
+	}{
+		"unchanged":         {name: "Engineering", modifiedBy: admin},
+		"changed by admin":  {name: "Research", modifiedBy: admin, warning: true},
+		"changed by itself": {name: "Research", modifiedBy: self},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/services/data/v55.0/sobjects/UserRole/00E000000000001AAA":
+					_, _ = fmt.Fprintf(w, `{"attributes":{"type":"UserRole"},"Id":"00E000000000001AAA","Name":%q,"DeveloperName":"Engineering","ParentRoleId":null,"LastModifiedDate":"2022-06-02T08:00:00.000+0000","LastModifiedById":%q}`, tc.name, tc.modifiedBy)
+				case "/services/data/v55.0/query":
+					q := r.URL.Query().Get("q")
+					queries = append(queries, q)
+					switch {
+					case strings.Contains(q, "FROM User "):
+						_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Name":"Jane Admin","Username":"jane@example.com"}]}`))
+					case strings.Contains(q, "FROM SetupAuditTrail "):
+						if !strings.Contains(q, "CreatedDate >= 2022-06-01T12:34:56Z") || !strings.Contains(q, "CreatedDate <= 2022-06-02T08:01:00Z") {
+							t.Errorf("unexpected audit trail period %s", q)
+						}
+						_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"CreatedDate":"2022-06-02T08:00:01.000+0000","Section":"Manage Users","Display":"Changed role name from Engineering to Research"}]}`))
+					default:
+						t.Errorf("unexpected query %s", q)
+					}
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			schema, diags := userRoleType{}.GetSchema(ctx)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			client := rest.NewClient(server.Client(), server.URL, "55.0", rest.RetryPolicy{})
+			client.SetIdentityUrl("https://login.salesforce.com/id/00D000000000001AAA/" + self)
+			r := &userRoleResource{Resource: Resource{
+				Client: client,
+				Batch:  rest.NewBatcher(client),
+				Data:   &userRoleResourceData{},
+			}}
+
+			state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+				"id":                 tftypes.NewValue(tftypes.String, "00E000000000001AAA"),
+				"name":               tftypes.NewValue(tftypes.String, "Engineering"),
+				"developer_name":     tftypes.NewValue(tftypes.String, "Engineering"),
+				"last_modified_date": tftypes.NewValue(tftypes.String, "2022-06-01T12:34:56.000+0000"),
+			})}
+			resp := &tfsdk.ReadResourceResponse{State: state}
+			r.Read(ctx, tfsdk.ReadResourceRequest{State: state}, resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatal(resp.Diagnostics)
+			}
+
+			if !tc.warning {
+				if len(resp.Diagnostics) != 0 || len(queries) != 0 {
+					t.Errorf("expected no warning nor queries, got %v %v", resp.Diagnostics, queries)
+				}
+				return
+			}
+			if len(resp.Diagnostics) != 1 {
+				t.Fatalf("expected a warning, got %v", resp.Diagnostics)
+			}
+			warning := resp.Diagnostics[0]
+			if warning.Summary() != "UserRole modified outside of Terraform" {
+				t.Errorf("unexpected summary %q", warning.Summary())
+			}
+			for _, s := range []string{"2022-06-02T08:00:00Z", "Jane Admin (jane@example.com, " + admin + ")", "Changed role name from Engineering to Research"} {
+				if !strings.Contains(warning.Detail(), s) {
+					t.Errorf("expected %q in %q", s, warning.Detail())
+				}
+			}
+		})
+	}
+}
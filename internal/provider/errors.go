@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+// fieldPaths maps the API name of a field to the path of its attribute, nil if it has none
+type fieldPaths func(field string) *tftypes.AttributePath
+
+// structFieldPaths maps the fields of a struct with both a tfsdk and a force tag, the API name
+// is the name in the force tag or the name of the struct field
+func structFieldPaths(v interface{}) fieldPaths {
+	paths := make(map[string]*tftypes.AttributePath)
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		attr := field.Tag.Get("tfsdk")
+		apiName := strings.Split(field.Tag.Get("force"), ",")[0]
+		if attr == "" || attr == "-" || apiName == "-" {
+			continue
+		}
+		if apiName == "" {
+			apiName = field.Name
+		}
+		paths[strings.ToLower(apiName)] = tftypes.NewAttributePath().WithAttributeName(attr)
+	}
+	return func(field string) *tftypes.AttributePath {
+		return paths[strings.ToLower(field)]
+	}
+}
+
+// addApiError adds err to the diagnostics, errors reported by Salesforce on specific fields are
+// attached to the matching attributes so they are shown next to them in the plan output
+func addApiError(diags *diag.Diagnostics, summary string, err error, paths fieldPaths) {
+	var apiErrors *rest.Errors
+	if !errors.As(err, &apiErrors) {
+		diags.AddError(summary, err.Error())
+		return
+	}
+	for _, apiErr := range apiErrors.Errors {
+		attached := false
+		if paths != nil {
+			for _, field := range apiErr.FieldNames() {
+				if path := paths(field); path != nil {
+					diags.AddAttributeError(path, summary, apiErr.Error())
+					attached = true
+				}
+			}
+		}
+		if !attached {
+			diags.AddError(summary, apiErr.Error())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+func TestAddApiError(t *testing.T) {
+	cases := map[string]struct {
+		err   error
+		paths fieldPaths
+		// expected attribute path of each diagnostic, nil for errors without attribute
+		expected []*tftypes.AttributePath
+	}{
+		"duplicate username": {
+			err:      &rest.Errors{Errors: []*rest.Error{{ErrorCode: "DUPLICATE_USERNAME", Message: "Duplicate Username."}}},
+			paths:    structFieldPaths(&userResourceData{}),
+			expected: []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("username")},
+		},
+		"multiple fields": {
+			err:   &rest.Errors{Errors: []*rest.Error{{ErrorCode: "REQUIRED_FIELD_MISSING", Fields: []string{"LastName", "ProfileId"}}}},
+			paths: structFieldPaths(&userResourceData{}),
+			expected: []*tftypes.AttributePath{
+				tftypes.NewAttributePath().WithAttributeName("last_name"),
+				tftypes.NewAttributePath().WithAttributeName("profile_id"),
+			},
+		},
+		"profile permission": {
+			err:      &rest.Errors{Errors: []*rest.Error{{ErrorCode: "FIELD_INTEGRITY_EXCEPTION", Fields: []string{"PermissionsApiEnabled"}}}},
+			paths:    profileFieldPaths,
+			expected: []*tftypes.AttributePath{tftypes.NewAttributePath().WithAttributeName("permissions").WithElementKeyString("ApiEnabled")},
+		},
+		"unknown field": {
+			err:      &rest.Errors{Errors: []*rest.Error{{ErrorCode: "INVALID_FIELD", Fields: []string{"Foo"}}}},
+			paths:    structFieldPaths(&userResourceData{}),
+			expected: []*tftypes.AttributePath{nil},
+		},
+		"not an api error": {
+			err:      fmt.Errorf("connection refused"),
+			paths:    structFieldPaths(&userResourceData{}),
+			expected: []*tftypes.AttributePath{nil},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			addApiError(&diags, "Error", c.err, c.paths)
+			if len(diags) != len(c.expected) {
+				t.Fatalf("expected %d diagnostics, got %v", len(c.expected), diags)
+			}
+			for i, d := range diags {
+				withPath, ok := d.(diag.DiagnosticWithPath)
+				switch {
+				case c.expected[i] == nil && ok:
+					t.Errorf("expected no attribute path, got %s", withPath.Path())
+				case c.expected[i] != nil && (!ok || !withPath.Path().Equal(c.expected[i])):
+					t.Errorf("expected attribute path %s, got %v", c.expected[i], d)
+				}
+			}
+		})
+	}
+}
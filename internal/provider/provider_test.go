@@ -4,11 +4,19 @@
 package provider
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/exec"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 var providerFactories = map[string]func() (tfprotov6.ProviderServer, error){
@@ -18,10 +26,155 @@ var providerFactories = map[string]func() (tfprotov6.ProviderServer, error){
 }
 
 func testAccPreCheck(t *testing.T) {
-	testEnvVars := []string{"SALESFORCE_CLIENT_ID", "SALESFORCE_PRIVATE_KEY", "SALESFORCE_API_VERSION", "SALESFORCE_USERNAME"}
+	testEnvVars := []string{"SALESFORCE_CLIENT_ID", "SALESFORCE_PRIVATE_KEY", "SALESFORCE_USERNAME"}
 	for _, env := range testEnvVars {
 		if os.Getenv(env) == "" {
 			t.Fatalf("%s must be set for acceptance tests", env)
 		}
 	}
 }
+
+// testUnitPreCheck skips the tests running Terraform against a fake org when there is no Terraform
+// binary, the test framework would try to download one otherwise
+func testUnitPreCheck(t *testing.T) {
+	if os.Getenv("TF_ACC_TERRAFORM_PATH") != "" {
+		return
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform must be on the PATH or TF_ACC_TERRAFORM_PATH must be set to run Terraform against the fake org")
+	}
+}
+
+// testUnitProviderConfig configures the provider to log in to the fake org with the password flow
+func testUnitProviderConfig(org *fakeorg.Org) string {
+	return fmt.Sprintf(`
+provider "salesforce" {
+  auth_type      = "password"
+  login_url      = %q
+  client_id      = "fakeorg"
+  client_secret  = "fakeorg"
+  username       = %q
+  password       = %q
+  max_retry_wait = "1s"
+}
+`, org.URL(), fakeorg.Username, fakeorg.Password)
+}
+
+// testFakeOrgProvider returns the provider configured as by testUnitProviderConfig, for tests
+// calling the resources directly
+func testFakeOrgProvider(t *testing.T, org *fakeorg.Org) *provider {
+	t.Helper()
+	p, diags := testConfigureFakeOrg(t, org, nil)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return p
+}
+
+// testConfigureFakeOrg configures the provider for the fake org, the attributes override the
+// configuration of testUnitProviderConfig
+func testConfigureFakeOrg(t *testing.T, org *fakeorg.Org, attributes map[string]tftypes.Value) (*provider, diag.Diagnostics) {
+	t.Helper()
+	ctx := context.Background()
+	p := New().(*provider)
+	schema, diags := p.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	values := map[string]tftypes.Value{
+		"auth_type":      tftypes.NewValue(tftypes.String, "password"),
+		"login_url":      tftypes.NewValue(tftypes.String, org.URL()),
+		"client_id":      tftypes.NewValue(tftypes.String, "fakeorg"),
+		"client_secret":  tftypes.NewValue(tftypes.String, "fakeorg"),
+		"username":       tftypes.NewValue(tftypes.String, fakeorg.Username),
+		"password":       tftypes.NewValue(tftypes.String, fakeorg.Password),
+		"max_retry_wait": tftypes.NewValue(tftypes.String, "1s"),
+	}
+	for name, value := range attributes {
+		values[name] = value
+	}
+	config := tfsdk.Config{Schema: schema, Raw: testObject(t, schema, values)}
+	resp := &tfsdk.ConfigureProviderResponse{}
+	p.Configure(ctx, tfsdk.ConfigureProviderRequest{Config: config}, resp)
+	return p, resp.Diagnostics
+}
+
+// testFakeOrgResource returns the schema of the resource type and a constructor of its resources
+// for the provider configured for the fake org. Terraform creates a new resource for every
+// request, the tests do the same
+func testFakeOrgResource(t *testing.T, org *fakeorg.Org, resourceType tfsdk.ResourceType) (tfsdk.Schema, func() tfsdk.Resource) {
+	t.Helper()
+	ctx := context.Background()
+	p := testFakeOrgProvider(t, org)
+	schema, diags := resourceType.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return schema, func() tfsdk.Resource {
+		r, diags := resourceType.NewResource(ctx, p)
+		if diags.HasError() {
+			t.Fatal(diags)
+		}
+		return r
+	}
+}
+
+// testFakeOrgDataSource returns a function reading the data source type from the fake org with
+// the attributes as configuration
+func testFakeOrgDataSource(t *testing.T, org *fakeorg.Org, dataSourceType tfsdk.DataSourceType) func(attributes map[string]tftypes.Value) *tfsdk.ReadDataSourceResponse {
+	t.Helper()
+	ctx := context.Background()
+	d, diags := dataSourceType.NewDataSource(ctx, testFakeOrgProvider(t, org))
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	schema, diags := dataSourceType.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+	return func(attributes map[string]tftypes.Value) *tfsdk.ReadDataSourceResponse {
+		config := tfsdk.Config{Schema: schema, Raw: testObject(t, schema, attributes)}
+		resp := &tfsdk.ReadDataSourceResponse{State: tfsdk.State{Schema: schema, Raw: config.Raw}}
+		d.Read(ctx, tfsdk.ReadDataSourceRequest{Config: config}, resp)
+		return resp
+	}
+}
+
+func TestProviderConfigure_unsupportedApiVersion(t *testing.T) {
+	org := fakeorg.New(t)
+	for _, version := range []string{"52.0", "99.0"} {
+		_, diags := testConfigureFakeOrg(t, org, map[string]tftypes.Value{
+			"api_version": tftypes.NewValue(tftypes.String, version),
+		})
+		if len(diags) != 1 {
+			t.Fatalf("expected a single diagnostic for api_version %s, got %v", version, diags)
+		}
+		attrDiag, ok := diags[0].(diag.DiagnosticWithPath)
+		if !ok || !attrDiag.Path().Equal(tftypes.NewAttributePath().WithAttributeName("api_version")) {
+			t.Errorf("expected an error on api_version %s, got %v", version, diags[0])
+		}
+	}
+}
+
+func TestProviderConfigure_jwtExpiry(t *testing.T) {
+	org := fakeorg.New(t)
+	for _, expiry := range []string{"0s", "3m1s", "5m", "3 minutes"} {
+		_, diags := testConfigureFakeOrg(t, org, map[string]tftypes.Value{
+			"jwt_expiry": tftypes.NewValue(tftypes.String, expiry),
+		})
+		if len(diags) != 1 {
+			t.Fatalf("expected a single diagnostic for jwt_expiry %s, got %v", expiry, diags)
+		}
+		attrDiag, ok := diags[0].(diag.DiagnosticWithPath)
+		if !ok || !attrDiag.Path().Equal(tftypes.NewAttributePath().WithAttributeName("jwt_expiry")) || !strings.Contains(attrDiag.Detail(), "at most 3m,") {
+			t.Errorf("expected an error on jwt_expiry %s, got %v", expiry, diags[0])
+		}
+	}
+
+	// the default is also the maximum
+	if _, diags := testConfigureFakeOrg(t, org, map[string]tftypes.Value{
+		"jwt_expiry": tftypes.NewValue(tftypes.String, "3m"),
+	}); diags.HasError() {
+		t.Errorf("expected jwt_expiry 3m to be accepted, got %v", diags)
+	}
+}
@@ -6,104 +6,187 @@ package provider
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
-	"github.com/nimajalali/go-force/force"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
+// lastModifiedDateLayout is the format of the datetime fields returned by the REST API
+const lastModifiedDateLayout = "2006-01-02T15:04:05.000-0700"
+
 type ResourceData interface {
-	Instance() force.SObject
-	Insertable() force.SObject
-	Updatable() force.SObject
+	Instance() rest.SObject
+	Insertable() rest.SObject
+	Updatable() rest.SObject
 	SetId(string)
 	GetId() string
 }
 
+// VersionedData is implemented by resource data that keeps the LastModifiedDate of the record
+// in the computed last_modified_date attribute, the data maps the field twice: to the attribute
+// and to a field decoded from Salesforce but never sent
+type VersionedData interface {
+	GetLastModifiedDate() string
+	// GetLastModifiedById returns the user who last modified the record, as read from Salesforce
+	GetLastModifiedById() string
+	// SyncLastModifiedDate copies the LastModifiedDate read from Salesforce to the attribute
+	SyncLastModifiedDate()
+}
+
 type Resource struct {
-	Client              *force.ForceApi
+	Client              *rest.Client
+	Batch               *rest.Batcher
 	Data                ResourceData
 	NeedsGetAfterUpsert bool
+	// OptimisticConcurrency rejects the update of VersionedData records that were modified
+	// in Salesforce after they were last read
+	OptimisticConcurrency bool
 }
 
 func (r *Resource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
+	ctx, span := startSpan(ctx, r.Data.Instance().ApiName(), "Create")
+	defer func() { endSpan(span, r.Data.GetId(), resp.Diagnostics) }()
+	r.create(ctx, req, resp)
+}
+
+func (r *Resource) create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
 	sobject := r.Data.Instance()
 	if diags := req.Plan.Get(ctx, sobject); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	sfResp, err := r.Client.InsertSObject(r.Data.Insertable())
+	id, err := r.Batch.Insert(ctx, r.Data.Insertable())
 	if err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("Error Inserting %s", sobject.ApiName()), err.Error())
+		addApiError(&resp.Diagnostics, fmt.Sprintf("Error Inserting %s", sobject.ApiName()), err, structFieldPaths(sobject))
 		return
 	}
-	r.Data.SetId(sfResp.Id)
+	r.Data.SetId(id)
 
 	if r.NeedsGetAfterUpsert {
-		if err := r.Client.GetSObject(r.Data.GetId(), nil, sobject); err != nil {
-			resp.Diagnostics.AddError(fmt.Sprintf("Error Getting %s", sobject.ApiName()), err.Error())
+		if err := r.Client.Get(ctx, r.Data.GetId(), nil, sobject); err != nil {
+			addApiError(&resp.Diagnostics, fmt.Sprintf("Error Getting %s", sobject.ApiName()), err, structFieldPaths(sobject))
 			return
 		}
 	}
+	if err := r.readVersion(ctx, sobject); err != nil {
+		addApiError(&resp.Diagnostics, fmt.Sprintf("Error Getting %s", sobject.ApiName()), err, structFieldPaths(sobject))
+		return
+	}
 
 	resp.Diagnostics = resp.State.Set(ctx, sobject)
 }
 
 func (r *Resource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
+	ctx, span := startSpan(ctx, r.Data.Instance().ApiName(), "Read")
+	defer func() { endSpan(span, r.Data.GetId(), resp.Diagnostics) }()
+	r.read(ctx, req, resp)
+}
+
+func (r *Resource) read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
 	sobject := r.Data.Instance()
 	if diags := req.State.Get(ctx, sobject); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	if err := r.Client.GetSObject(r.Data.GetId(), nil, sobject); err != nil {
+	// concurrent reads of the same SObject type are coalesced into a single query
+	if err := r.Batch.Get(ctx, r.Data.GetId(), sobject); err != nil {
 		if isNotFoundError(err) {
 			resp.State.RemoveResource(ctx)
 		} else {
-			resp.Diagnostics.AddError(fmt.Sprintf("Error Getting %s", sobject.ApiName()), err.Error())
+			addApiError(&resp.Diagnostics, fmt.Sprintf("Error Getting %s", sobject.ApiName()), err, structFieldPaths(sobject))
 		}
 		return
 	}
+	if versioned, ok := r.Data.(VersionedData); ok {
+		versioned.SyncLastModifiedDate()
+	}
 
 	resp.Diagnostics = resp.State.Set(ctx, sobject)
+	if !resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(r.driftWarning(ctx, req.State, resp.State)...)
+	}
 }
 
 func (r *Resource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
+	ctx, span := startSpan(ctx, r.Data.Instance().ApiName(), "Update")
+	defer func() { endSpan(span, r.Data.GetId(), resp.Diagnostics) }()
+	r.update(ctx, req, resp)
+}
+
+func (r *Resource) update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
 	sobject := r.Data.Instance()
 	if diags := req.Plan.Get(ctx, sobject); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	if err := r.Client.UpdateSObject(r.Data.GetId(), r.Data.Updatable()); err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("Error Updating %s", sobject.ApiName()), err.Error())
+	lastModified, diags := r.stateVersion(ctx, req.State)
+	if diags.HasError() {
+		resp.Diagnostics = diags
+		return
+	}
+
+	var err error
+	if lastModified.IsZero() {
+		err = r.Batch.Update(ctx, r.Data.GetId(), r.Data.Updatable())
+	} else {
+		// conditional updates can't be batched, collections don't support If-Unmodified-Since
+		err = r.Client.UpdateIfUnmodifiedSince(ctx, r.Data.GetId(), r.Data.Updatable(), lastModified)
+	}
+	if rest.IsPreconditionFailed(err) {
+		resp.Diagnostics.AddError(
+			"Record changed since plan",
+			fmt.Sprintf("The %s %s was modified in Salesforce after it was last read by Terraform at %s, applying the plan would overwrite those changes. "+
+				"Run terraform plan again to review the changes, or set optimistic_concurrency = false in the provider configuration to overwrite them.",
+				sobject.ApiName(), r.Data.GetId(), lastModified.Format(time.RFC3339)),
+		)
+		return
+	}
+	if err != nil {
+		addApiError(&resp.Diagnostics, fmt.Sprintf("Error Updating %s", sobject.ApiName()), err, structFieldPaths(sobject))
 		return
 	}
 
 	if r.NeedsGetAfterUpsert {
-		if err := r.Client.GetSObject(r.Data.GetId(), nil, sobject); err != nil {
+		if err := r.Client.Get(ctx, r.Data.GetId(), nil, sobject); err != nil {
 			if isNotFoundError(err) {
 				resp.State.RemoveResource(ctx)
 			} else {
-				resp.Diagnostics.AddError(fmt.Sprintf("Error Getting %s", sobject.ApiName()), err.Error())
+				addApiError(&resp.Diagnostics, fmt.Sprintf("Error Getting %s", sobject.ApiName()), err, structFieldPaths(sobject))
 			}
 			return
 		}
 	}
+	if err := r.readVersion(ctx, sobject); err != nil {
+		addApiError(&resp.Diagnostics, fmt.Sprintf("Error Getting %s", sobject.ApiName()), err, structFieldPaths(sobject))
+		return
+	}
 
 	resp.Diagnostics = resp.State.Set(ctx, sobject)
 }
 
 func (r *Resource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
+	ctx, span := startSpan(ctx, r.Data.Instance().ApiName(), "Delete")
+	defer func() { endSpan(span, r.Data.GetId(), resp.Diagnostics) }()
+	r.delete(ctx, req, resp)
+}
+
+func (r *Resource) delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
 	sobject := r.Data.Instance()
 	if diags := req.State.Get(ctx, sobject); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	if err := r.Client.DeleteSObject(r.Data.GetId(), sobject); err != nil {
+	if err := r.Batch.Delete(ctx, r.Data.GetId(), sobject); err != nil {
 		if !isNotFoundError(err) {
-			resp.Diagnostics.AddError(fmt.Sprintf("Error Deleting %s", sobject.ApiName()), err.Error())
+			addApiError(&resp.Diagnostics, fmt.Sprintf("Error Deleting %s", sobject.ApiName()), err, structFieldPaths(sobject))
 			return
 		}
 	}
@@ -112,13 +195,61 @@ func (r *Resource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest,
 }
 
 func (r *Resource) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
+	ctx, span := startSpan(ctx, r.Data.Instance().ApiName(), "ImportState")
+	defer func() { endSpan(span, r.Data.GetId(), resp.Diagnostics) }()
+	r.importState(ctx, req, resp)
+}
+
+func (r *Resource) importState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
 	sobject := r.Data.Instance()
 	id := normalizeId(req.ID)
-	if err := r.Client.GetSObject(id, nil, sobject); err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("Error Importing %s", sobject.ApiName()), err.Error())
+	if err := r.Client.Get(ctx, id, nil, sobject); err != nil {
+		addApiError(&resp.Diagnostics, fmt.Sprintf("Error Importing %s", sobject.ApiName()), err, structFieldPaths(sobject))
 		return
 	}
 	r.Data.SetId(id)
+	if versioned, ok := r.Data.(VersionedData); ok {
+		versioned.SyncLastModifiedDate()
+	}
 
 	resp.Diagnostics = resp.State.Set(ctx, sobject)
 }
+
+// readVersion reads the LastModifiedDate of a record that was just written, when optimistic
+// concurrency is disabled the request is skipped and last_modified_date is left null
+func (r *Resource) readVersion(ctx context.Context, sobject rest.SObject) error {
+	versioned, ok := r.Data.(VersionedData)
+	if !ok {
+		return nil
+	}
+	if r.OptimisticConcurrency {
+		if err := r.Client.Get(ctx, r.Data.GetId(), []string{"LastModifiedDate"}, sobject); err != nil {
+			return err
+		}
+	}
+	versioned.SyncLastModifiedDate()
+	return nil
+}
+
+// stateVersion returns the LastModifiedDate of the record when it was last read, the zero time
+// when optimistic concurrency is disabled or the record was never read with it
+func (r *Resource) stateVersion(ctx context.Context, state tfsdk.State) (time.Time, diag.Diagnostics) {
+	if _, ok := r.Data.(VersionedData); !ok || !r.OptimisticConcurrency {
+		return time.Time{}, nil
+	}
+	var lastModified types.String
+	path := tftypes.NewAttributePath().WithAttributeName("last_modified_date")
+	if diags := state.GetAttribute(ctx, path, &lastModified); diags.HasError() {
+		return time.Time{}, diags
+	}
+	if lastModified.Null || lastModified.Unknown || lastModified.Value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(lastModifiedDateLayout, lastModified.Value)
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddAttributeError(path, "Invalid last_modified_date", fmt.Sprintf("Unable to parse %q: %s", lastModified.Value, err))
+		return time.Time{}, diags
+	}
+	return t, nil
+}
@@ -11,7 +11,8 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/nimajalali/go-force/force"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 type profileType struct {
@@ -70,11 +71,13 @@ func (p profileType) NewResource(_ context.Context, prov tfsdk.Provider) (tfsdk.
 	}
 	return &profileResource{
 		client: provider.client,
+		batch:  provider.batch,
 	}, nil
 }
 
 type profileResource struct {
-	client *force.ForceApi
+	client *rest.Client
+	batch  *rest.Batcher
 }
 
 type profileResourceData struct {
@@ -147,6 +150,14 @@ func (p profileMap) ToStateData(includePermissions ...string) profileResourceDat
 	return data
 }
 
+// profileFieldPaths maps the flattened permission fields back into the permissions map
+func profileFieldPaths(field string) *tftypes.AttributePath {
+	if strings.HasPrefix(field, "Permissions") {
+		return tftypes.NewAttributePath().WithAttributeName("permissions").WithElementKeyString(strings.TrimPrefix(field, "Permissions"))
+	}
+	return structFieldPaths(profileResourceData{})(field)
+}
+
 func (profileMap) ApiName() string {
 	return "Profile"
 }
@@ -157,34 +168,38 @@ func (profileMap) ExternalIdApiName() string {
 
 func (p *profileResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
 	var data profileResourceData
+	ctx, span := startSpan(ctx, "Profile", "Create")
+	defer func() { endSpan(span, data.Id.Value, resp.Diagnostics) }()
 	if diags := req.Plan.Get(ctx, &data); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	sfResp, err := p.client.InsertSObject(data.ToMap())
+	id, err := p.batch.Insert(ctx, data.ToMap())
 	if err != nil {
-		resp.Diagnostics.AddError("Error Inserting Profile", err.Error())
+		addApiError(&resp.Diagnostics, "Error Inserting Profile", err, profileFieldPaths)
 		return
 	}
-	data.Id = types.String{Value: sfResp.Id}
+	data.Id = types.String{Value: id}
 
 	resp.Diagnostics = resp.State.Set(ctx, &data)
 }
 
 func (p *profileResource) Read(ctx context.Context, req tfsdk.ReadResourceRequest, resp *tfsdk.ReadResourceResponse) {
 	var data profileResourceData
+	ctx, span := startSpan(ctx, "Profile", "Read")
+	defer func() { endSpan(span, data.Id.Value, resp.Diagnostics) }()
 	if diags := req.State.Get(ctx, &data); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
 	var pMap profileMap
-	if err := p.client.GetSObject(data.Id.Value, nil, &pMap); err != nil {
+	if err := p.client.Get(ctx, data.Id.Value, nil, &pMap); err != nil {
 		if isNotFoundError(err) {
 			resp.State.RemoveResource(ctx)
 		} else {
-			resp.Diagnostics.AddError("Error Getting Profile", err.Error())
+			addApiError(&resp.Diagnostics, "Error Getting Profile", err, profileFieldPaths)
 		}
 		return
 	}
@@ -198,13 +213,15 @@ func (p *profileResource) Read(ctx context.Context, req tfsdk.ReadResourceReques
 
 func (p *profileResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
 	var data profileResourceData
+	ctx, span := startSpan(ctx, "Profile", "Update")
+	defer func() { endSpan(span, data.Id.Value, resp.Diagnostics) }()
 	if diags := req.Plan.Get(ctx, &data); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	if err := p.client.UpdateSObject(data.Id.Value, data.ToMap("UserLicenseId")); err != nil {
-		resp.Diagnostics.AddError("Error Updating Profile", err.Error())
+	if err := p.batch.Update(ctx, data.Id.Value, data.ToMap("UserLicenseId")); err != nil {
+		addApiError(&resp.Diagnostics, "Error Updating Profile", err, profileFieldPaths)
 		return
 	}
 
@@ -213,14 +230,16 @@ func (p *profileResource) Update(ctx context.Context, req tfsdk.UpdateResourceRe
 
 func (p *profileResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
 	var data profileResourceData
+	ctx, span := startSpan(ctx, "Profile", "Delete")
+	defer func() { endSpan(span, data.Id.Value, resp.Diagnostics) }()
 	if diags := req.State.Get(ctx, &data); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
-	if err := p.client.DeleteSObject(data.Id.Value, data.ToMap()); err != nil {
+	if err := p.batch.Delete(ctx, data.Id.Value, data.ToMap()); err != nil {
 		if !isNotFoundError(err) {
-			resp.Diagnostics.AddError("Error Deleting Profile", err.Error())
+			addApiError(&resp.Diagnostics, "Error Deleting Profile", err, profileFieldPaths)
 			return
 		}
 	}
@@ -230,9 +249,11 @@ func (p *profileResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRe
 
 func (p *profileResource) ImportState(ctx context.Context, req tfsdk.ImportResourceStateRequest, resp *tfsdk.ImportResourceStateResponse) {
 	id := normalizeId(req.ID)
+	ctx, span := startSpan(ctx, "Profile", "ImportState")
+	defer func() { endSpan(span, id, resp.Diagnostics) }()
 	var pMap profileMap
-	if err := p.client.GetSObject(id, nil, &pMap); err != nil {
-		resp.Diagnostics.AddError("Error Importing Profile", err.Error())
+	if err := p.client.Get(ctx, id, nil, &pMap); err != nil {
+		addApiError(&resp.Diagnostics, "Error Importing Profile", err, profileFieldPaths)
 		return
 	}
 	data := pMap.ToStateData()
@@ -4,11 +4,15 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 func TestAccResourceProfile_basic(t *testing.T) {
@@ -68,6 +72,110 @@ func TestAccResourceProfile_update(t *testing.T) {
 	})
 }
 
+func TestUnitResourceProfile_update(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceProfile_basic("tf-test"),
+				// TODO there is a bug around maps that causes a permadiff for empty maps
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceProfile_with_permissions("tf-test"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("salesforce_profile.test", "description", "test update"),
+					resource.TestCheckResourceAttr("salesforce_profile.test", "permissions.%", "2"),
+					resource.TestCheckResourceAttr("salesforce_profile.test", "permissions.EditTask", "true"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceProfile_fakeOrg(t *testing.T) {
+	ctx := context.Background()
+	org := fakeorg.New(t)
+	schema, newResource := testFakeOrgResource(t, org, profileType{})
+	license := org.Insert("UserLicense", map[string]interface{}{"Name": "Identity", "LicenseDefinitionKey": "PID_Identity"})
+	permissions := func(editTask bool) tftypes.Value {
+		return tftypes.NewValue(tftypes.Map{ElementType: tftypes.Bool}, map[string]tftypes.Value{
+			"EditTask":    tftypes.NewValue(tftypes.Bool, editTask),
+			"ViewAllData": tftypes.NewValue(tftypes.Bool, true),
+		})
+	}
+
+	plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"name":            tftypes.NewValue(tftypes.String, "Auditors"),
+		"user_license_id": tftypes.NewValue(tftypes.String, license),
+		"permissions":     permissions(false),
+	})}
+	created := &tfsdk.CreateResourceResponse{State: tfsdk.State{Schema: schema, Raw: tftypes.NewValue(schema.TerraformType(ctx), nil)}}
+	newResource().Create(ctx, tfsdk.CreateResourceRequest{Plan: plan}, created)
+	if created.Diagnostics.HasError() {
+		t.Fatal(created.Diagnostics)
+	}
+	var data profileResourceData
+	if diags := created.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	record := org.Record("Profile", data.Id.Value)
+	if record == nil || record["Name"] != "Auditors" || record["PermissionsEditTask"] != false || record["PermissionsViewAllData"] != true {
+		t.Fatalf("expected the profile to be created with its permissions, got %v", record)
+	}
+
+	// only the permissions in state are read back
+	org.Update("Profile", data.Id.Value, map[string]interface{}{"Description": "Read only access", "PermissionsEditTask": true, "PermissionsModifyAllData": true})
+	refreshed := &tfsdk.ReadResourceResponse{State: created.State}
+	newResource().Read(ctx, tfsdk.ReadResourceRequest{State: created.State}, refreshed)
+	if refreshed.Diagnostics.HasError() {
+		t.Fatal(refreshed.Diagnostics)
+	}
+	if !refreshed.State.Raw.Equal(testObject(t, schema, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, data.Id.Value),
+		"name":            tftypes.NewValue(tftypes.String, "Auditors"),
+		"description":     tftypes.NewValue(tftypes.String, "Read only access"),
+		"user_license_id": tftypes.NewValue(tftypes.String, license),
+		"permissions":     permissions(true),
+	})) {
+		t.Errorf("unexpected refreshed state %v", refreshed.State.Raw)
+	}
+
+	plan = tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id":              tftypes.NewValue(tftypes.String, data.Id.Value),
+		"name":            tftypes.NewValue(tftypes.String, "Auditors"),
+		"description":     tftypes.NewValue(tftypes.String, "test update"),
+		"user_license_id": tftypes.NewValue(tftypes.String, license),
+		"permissions":     permissions(false),
+	})}
+	updated := &tfsdk.UpdateResourceResponse{State: refreshed.State}
+	newResource().Update(ctx, tfsdk.UpdateResourceRequest{Plan: plan, State: refreshed.State}, updated)
+	if updated.Diagnostics.HasError() {
+		t.Fatal(updated.Diagnostics)
+	}
+	record = org.Record("Profile", data.Id.Value)
+	if record["Description"] != "test update" || record["PermissionsEditTask"] != false || record["PermissionsModifyAllData"] != true {
+		t.Errorf("expected the profile to be updated, got %v", record)
+	}
+
+	deleted := &tfsdk.DeleteResourceResponse{State: updated.State}
+	newResource().Delete(ctx, tfsdk.DeleteResourceRequest{State: updated.State}, deleted)
+	if deleted.Diagnostics.HasError() {
+		t.Fatal(deleted.Diagnostics)
+	}
+	if !deleted.State.Raw.IsNull() {
+		t.Error("expected the profile to be removed from state")
+	}
+	if record := org.Record("Profile", data.Id.Value); record != nil {
+		t.Errorf("expected the profile to be deleted, got %v", record)
+	}
+}
+
 func testAccResourceProfile_basic(name string) string {
 	return fmt.Sprintf(`
 data "salesforce_user_license" "standard" {
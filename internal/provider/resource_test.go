@@ -0,0 +1,115 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+)
+
+// testObject returns a value of the schema with the given attributes, the others are null
+func testObject(t *testing.T, schema tfsdk.Schema, attributes map[string]tftypes.Value) tftypes.Value {
+	t.Helper()
+	objectType := schema.TerraformType(context.Background()).(tftypes.Object)
+	values := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, typ := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(typ, nil)
+	}
+	for name, value := range attributes {
+		if _, ok := values[name]; !ok {
+			t.Fatalf("unknown attribute %s", name)
+		}
+		values[name] = value
+	}
+	return tftypes.NewValue(objectType, values)
+}
+
+func TestResource_optimisticConcurrency(t *testing.T) {
+	const lastModified = "2022-06-01T12:34:56.000+0000"
+	for name, tc := range map[string]struct {
+		enabled  bool
+		modified bool
+		header   string
+		error    string
+	}{
+		"unmodified": {enabled: true, header: "Wed, 01 Jun 2022 12:34:56 GMT"},
+		"modified":   {enabled: true, modified: true, header: "Wed, 01 Jun 2022 12:34:56 GMT", error: "Record changed since plan"},
+		"disabled":   {enabled: false, modified: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodPatch:
+					if header := r.Header.Get("If-Unmodified-Since"); header != tc.header {
+						t.Errorf("unexpected If-Unmodified-Since %q", header)
+					}
+					if tc.modified && tc.header != "" {
+						w.WriteHeader(http.StatusPreconditionFailed)
+						return
+					}
+					w.WriteHeader(http.StatusNoContent)
+				case http.MethodGet:
+					if !tc.enabled {
+						t.Error("unexpected read of LastModifiedDate")
+					}
+					_, _ = w.Write([]byte(`{"attributes":{"type":"UserRole"},"Id":"00E000000000001AAA","LastModifiedDate":"2022-06-02T08:00:00.000+0000"}`))
+				}
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			schema, diags := userRoleType{}.GetSchema(ctx)
+			if diags.HasError() {
+				t.Fatal(diags)
+			}
+			client := rest.NewClient(server.Client(), server.URL, "55.0", rest.RetryPolicy{})
+			r := &userRoleResource{Resource: Resource{
+				Client:                client,
+				Batch:                 rest.NewBatcher(client),
+				Data:                  &userRoleResourceData{},
+				OptimisticConcurrency: tc.enabled,
+			}}
+
+			attributes := map[string]tftypes.Value{
+				"id":                 tftypes.NewValue(tftypes.String, "00E000000000001AAA"),
+				"name":               tftypes.NewValue(tftypes.String, "Engineering"),
+				"developer_name":     tftypes.NewValue(tftypes.String, "Engineering"),
+				"last_modified_date": tftypes.NewValue(tftypes.String, lastModified),
+			}
+			state := tfsdk.State{Schema: schema, Raw: testObject(t, schema, attributes)}
+			attributes["name"] = tftypes.NewValue(tftypes.String, "Research")
+			attributes["last_modified_date"] = tftypes.NewValue(tftypes.String, tftypes.UnknownValue)
+			plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, attributes)}
+
+			resp := &tfsdk.UpdateResourceResponse{State: state}
+			r.Update(ctx, tfsdk.UpdateResourceRequest{State: state, Plan: plan}, resp)
+
+			if tc.error != "" {
+				if !resp.Diagnostics.HasError() || resp.Diagnostics[0].Summary() != tc.error {
+					t.Fatalf("expected %q error, got %v", tc.error, resp.Diagnostics)
+				}
+				return
+			}
+			if resp.Diagnostics.HasError() {
+				t.Fatal(resp.Diagnostics)
+			}
+			var data userRoleResourceData
+			if diags := resp.State.Get(ctx, &data); diags.HasError() {
+				t.Fatal(diags)
+			}
+			if tc.enabled && data.LastModifiedDate.Value != "2022-06-02T08:00:00.000+0000" {
+				t.Errorf("expected the new last_modified_date, got %v", data.LastModifiedDate)
+			}
+			if !tc.enabled && !data.LastModifiedDate.Null {
+				t.Errorf("expected a null last_modified_date, got %v", data.LastModifiedDate)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/attr"
@@ -14,7 +15,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-provider-salesforce/internal/picklists"
-	"github.com/nimajalali/go-force/force"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 var userDefaults = resourceDefaults{
@@ -49,6 +50,11 @@ func (userType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics) {
 					staticComputed{},
 				},
 			},
+			"last_modified_date": {
+				Description: "Date and time the record was last modified in Salesforce, as read by Terraform. Unless optimistic_concurrency is disabled in the provider, updates are rejected if the record was modified after this date. It is known after apply whenever the record is updated, and left null after an apply with optimistic_concurrency disabled until the next refresh.",
+				Type:        types.StringType,
+				Computed:    true,
+			},
 			"alias": {
 				Description: "The user’s alias. For example, jsmith.",
 				Type:        types.StringType,
@@ -190,27 +196,34 @@ func (u userType) NewResource(_ context.Context, p tfsdk.Provider) (tfsdk.Resour
 	return &userResource{
 		Resource: Resource{
 			Client: prov.client,
-			Data:   &userResourceData{},
+			Batch:  prov.batch,
+
+			OptimisticConcurrency: prov.optimisticConcurrency,
+			Data:                  &userResourceData{},
 		},
+		describe: prov.describe,
 	}, nil
 }
 
 type userResource struct {
 	Resource
+	describe *rest.DescribeCache
 }
 
-func (u *userResource) resetPassword(id string) error {
-	meta, err := u.Client.DescribeSObjects()
+func (u *userResource) resetPassword(ctx context.Context, id string) error {
+	meta, err := u.describe.Describe(ctx, "User")
 	if err != nil {
 		return err
 	}
-	uri := strings.Replace(meta["User"].URLs["rowTemplate"], "{ID}", id, 1) + "/password"
+	uri := strings.Replace(meta.URLs["rowTemplate"], "{ID}", id, 1) + "/password"
 
-	return u.Client.Delete(uri, nil)
+	return u.Client.Do(ctx, http.MethodDelete, uri, nil, nil, nil)
 }
 
 func (u *userResource) Create(ctx context.Context, req tfsdk.CreateResourceRequest, resp *tfsdk.CreateResourceResponse) {
-	u.Resource.Create(ctx, req, resp)
+	ctx, span := startSpan(ctx, "User", "Create")
+	defer func() { endSpan(span, u.Data.GetId(), resp.Diagnostics) }()
+	u.Resource.create(ctx, req, resp)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -220,7 +233,7 @@ func (u *userResource) Create(ctx context.Context, req tfsdk.CreateResourceReque
 		return
 	}
 	if data.ResetPassword {
-		if err := u.resetPassword(data.Id.Value); err != nil {
+		if err := u.resetPassword(ctx, data.Id.Value); err != nil {
 			resp.Diagnostics.AddWarning("Error Resetting Password", fmt.Sprintf("The user %s was succesfully created but the reset password request failed: %s", data.Username, err))
 		}
 	} else {
@@ -229,7 +242,9 @@ func (u *userResource) Create(ctx context.Context, req tfsdk.CreateResourceReque
 }
 
 func (u *userResource) Update(ctx context.Context, req tfsdk.UpdateResourceRequest, resp *tfsdk.UpdateResourceResponse) {
-	u.Resource.Update(ctx, req, resp)
+	ctx, span := startSpan(ctx, "User", "Update")
+	defer func() { endSpan(span, u.Data.GetId(), resp.Diagnostics) }()
+	u.Resource.update(ctx, req, resp)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -245,7 +260,7 @@ func (u *userResource) Update(ctx context.Context, req tfsdk.UpdateResourceReque
 	}
 	// only trigger password reset when going from false -> true
 	if !stateBeforeUpdate.ResetPassword && stateAfterUpdate.ResetPassword {
-		if err := u.resetPassword(stateAfterUpdate.Id.Value); err != nil {
+		if err := u.resetPassword(ctx, stateAfterUpdate.Id.Value); err != nil {
 			resp.Diagnostics.AddWarning("Error Resetting Password", fmt.Sprintf("The user %s was succesfully updated but the reset password request failed: %s", stateAfterUpdate.Username, err))
 		}
 	}
@@ -253,18 +268,20 @@ func (u *userResource) Update(ctx context.Context, req tfsdk.UpdateResourceReque
 
 func (u *userResource) Delete(ctx context.Context, req tfsdk.DeleteResourceRequest, resp *tfsdk.DeleteResourceResponse) {
 	var id string
+	ctx, span := startSpan(ctx, "User", "Delete")
+	defer func() { endSpan(span, id, resp.Diagnostics) }()
 	if diags := req.State.GetAttribute(ctx, tftypes.NewAttributePath().WithAttributeName("id"), &id); diags.HasError() {
 		resp.Diagnostics = diags
 		return
 	}
 
 	isActive := false
-	err := u.Client.UpdateSObject(id, userResourceData{IsActive: &isActive})
+	err := u.Batch.Update(ctx, id, userResourceData{IsActive: &isActive})
 	if err != nil {
 		if isNotFoundError(err) {
 			resp.State.RemoveResource(ctx)
 		} else {
-			resp.Diagnostics.AddError("Error Deleting User", err.Error())
+			addApiError(&resp.Diagnostics, "Error Deleting User", err, structFieldPaths(userResourceData{}))
 		}
 		return
 	}
@@ -287,6 +304,9 @@ type userResourceData struct {
 	ResetPassword     bool         `tfsdk:"reset_password" force:"-"`
 	IsActive          *bool        `tfsdk:"-" force:",omitempty"`
 	Id                types.String `tfsdk:"id" force:"-"`
+	LastModifiedDate  types.String `tfsdk:"last_modified_date" force:"-"`
+	LastModified      *string      `tfsdk:"-" force:"LastModifiedDate,omitempty"`
+	LastModifiedById  *string      `tfsdk:"-" force:",omitempty"`
 }
 
 func (userResourceData) ApiName() string {
@@ -297,16 +317,41 @@ func (userResourceData) ExternalIdApiName() string {
 	return ""
 }
 
-func (u *userResourceData) Instance() force.SObject {
+func (u *userResourceData) Instance() rest.SObject {
 	return u
 }
 
-func (u *userResourceData) Insertable() force.SObject {
-	return *u
+func (u *userResourceData) Insertable() rest.SObject {
+	v := *u
+	v.LastModified = nil
+	v.LastModifiedById = nil
+	return v
 }
 
-func (u *userResourceData) Updatable() force.SObject {
-	return *u
+func (u *userResourceData) Updatable() rest.SObject {
+	v := *u
+	v.LastModified = nil
+	v.LastModifiedById = nil
+	return v
+}
+
+func (u *userResourceData) GetLastModifiedDate() string {
+	return u.LastModifiedDate.Value
+}
+
+func (u *userResourceData) GetLastModifiedById() string {
+	if u.LastModifiedById == nil {
+		return ""
+	}
+	return *u.LastModifiedById
+}
+
+func (u *userResourceData) SyncLastModifiedDate() {
+	if u.LastModified == nil {
+		u.LastModifiedDate = types.String{Null: true}
+		return
+	}
+	u.LastModifiedDate = types.String{Value: *u.LastModified}
 }
 
 func (u *userResourceData) GetId() string {
@@ -9,7 +9,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
-	"github.com/nimajalali/go-force/force"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 type userRoleType struct {
@@ -27,6 +27,11 @@ func (userRoleType) GetSchema(_ context.Context) (tfsdk.Schema, diag.Diagnostics
 					staticComputed{},
 				},
 			},
+			"last_modified_date": {
+				Description: "Date and time the record was last modified in Salesforce, as read by Terraform. Unless optimistic_concurrency is disabled in the provider, updates are rejected if the record was modified after this date. It is known after apply whenever the record is updated, and left null after an apply with optimistic_concurrency disabled until the next refresh.",
+				Type:        types.StringType,
+				Computed:    true,
+			},
 			"name": {
 				Description: "Name of the role. Corresponds to Label on the user interface.",
 				Type:        types.StringType,
@@ -70,7 +75,10 @@ func (u userRoleType) NewResource(_ context.Context, prov tfsdk.Provider) (tfsdk
 	return &userRoleResource{
 		Resource: Resource{
 			Client: provider.client,
-			Data:   &userRoleResourceData{},
+			Batch:  provider.batch,
+
+			OptimisticConcurrency: provider.optimisticConcurrency,
+			Data:                  &userRoleResourceData{},
 		},
 	}, nil
 }
@@ -80,10 +88,13 @@ type userRoleResource struct {
 }
 
 type userRoleResourceData struct {
-	Name          string       `tfsdk:"name" force:",omitempty"`
-	DeveloperName string       `tfsdk:"developer_name" force:",omitempty"`
-	ParentRoleId  *string      `tfsdk:"parent_role_id"`
-	Id            types.String `tfsdk:"id" force:"-"`
+	Name             string       `tfsdk:"name" force:",omitempty"`
+	DeveloperName    string       `tfsdk:"developer_name" force:",omitempty"`
+	ParentRoleId     *string      `tfsdk:"parent_role_id"`
+	Id               types.String `tfsdk:"id" force:"-"`
+	LastModifiedDate types.String `tfsdk:"last_modified_date" force:"-"`
+	LastModified     *string      `tfsdk:"-" force:"LastModifiedDate,omitempty"`
+	LastModifiedById *string      `tfsdk:"-" force:",omitempty"`
 }
 
 func (userRoleResourceData) ApiName() string {
@@ -94,16 +105,41 @@ func (userRoleResourceData) ExternalIdApiName() string {
 	return ""
 }
 
-func (u *userRoleResourceData) Instance() force.SObject {
+func (u *userRoleResourceData) Instance() rest.SObject {
 	return u
 }
 
-func (u *userRoleResourceData) Insertable() force.SObject {
-	return *u
+func (u *userRoleResourceData) Insertable() rest.SObject {
+	v := *u
+	v.LastModified = nil
+	v.LastModifiedById = nil
+	return v
+}
+
+func (u *userRoleResourceData) Updatable() rest.SObject {
+	v := *u
+	v.LastModified = nil
+	v.LastModifiedById = nil
+	return v
 }
 
-func (u *userRoleResourceData) Updatable() force.SObject {
-	return *u
+func (u *userRoleResourceData) GetLastModifiedDate() string {
+	return u.LastModifiedDate.Value
+}
+
+func (u *userRoleResourceData) GetLastModifiedById() string {
+	if u.LastModifiedById == nil {
+		return ""
+	}
+	return *u.LastModifiedById
+}
+
+func (u *userRoleResourceData) SyncLastModifiedDate() {
+	if u.LastModified == nil {
+		u.LastModifiedDate = types.String{Null: true}
+		return
+	}
+	u.LastModifiedDate = types.String{Value: *u.LastModified}
 }
 
 func (u *userRoleResourceData) GetId() string {
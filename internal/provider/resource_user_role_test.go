@@ -4,11 +4,17 @@
 package provider
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 func TestAccResourceUserRole_basic(t *testing.T) {
@@ -65,6 +71,100 @@ func TestAccResourceUserRole_update(t *testing.T) {
 	})
 }
 
+func TestUnitResourceUserRole_update(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUserRole_basic("tf_test"),
+			},
+			{
+				ResourceName:      "salesforce_user_role.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUserRole_with_parent("tf_test_parent", "tf_test"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("salesforce_user_role.test", "parent_role_id", "salesforce_user_role.parent", "id"),
+				),
+			},
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUserRole_with_parent_no_assign("tf_test_parent", "tf_test"),
+			},
+		},
+	})
+}
+
+func TestUnitResourceUserRole_lockedRow(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	fault := fakeorg.UnableToLockRow(http.MethodPost, "UserRole", 1)
+	fault.RetryAfter = "0"
+	org.Inject(fault)
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUserRole_basic("tf_test"),
+			},
+		},
+	})
+}
+
+func TestResourceUserRole_fakeOrg(t *testing.T) {
+	ctx := context.Background()
+	org := fakeorg.New(t)
+	schema, newResource := testFakeOrgResource(t, org, userRoleType{})
+
+	plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id":                 tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"last_modified_date": tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"name":               tftypes.NewValue(tftypes.String, "Engineering"),
+		"developer_name":     tftypes.NewValue(tftypes.String, "Engineering"),
+	})}
+	created := &tfsdk.CreateResourceResponse{State: tfsdk.State{Schema: schema, Raw: tftypes.NewValue(schema.TerraformType(ctx), nil)}}
+	newResource().Create(ctx, tfsdk.CreateResourceRequest{Plan: plan}, created)
+	if created.Diagnostics.HasError() {
+		t.Fatal(created.Diagnostics)
+	}
+	var data userRoleResourceData
+	if diags := created.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if record := org.Record("UserRole", data.Id.Value); record == nil || record["LastModifiedDate"] != data.LastModifiedDate.Value {
+		t.Fatalf("expected the role to be created with its last modified date in state, got %v and %#v", record, data)
+	}
+
+	// a change made by another user is reported on refresh
+	other := org.Insert("User", map[string]interface{}{"Name": "Jane Doe", "Username": "jane@fakeorg.example.com"})
+	org.Update("UserRole", data.Id.Value, map[string]interface{}{"Name": "R&D", "LastModifiedById": other})
+	refreshed := &tfsdk.ReadResourceResponse{State: created.State}
+	newResource().Read(ctx, tfsdk.ReadResourceRequest{State: created.State}, refreshed)
+	if refreshed.Diagnostics.HasError() {
+		t.Fatal(refreshed.Diagnostics)
+	}
+	if len(refreshed.Diagnostics) != 1 || !strings.Contains(refreshed.Diagnostics[0].Detail(), "Jane Doe (jane@fakeorg.example.com") {
+		t.Errorf("expected a warning naming the user who modified the role, got %v", refreshed.Diagnostics)
+	}
+
+	// applying a plan made before the change would overwrite it
+	updated := &tfsdk.UpdateResourceResponse{State: created.State}
+	newResource().Update(ctx, tfsdk.UpdateResourceRequest{Plan: tfsdk.Plan{Schema: schema, Raw: created.State.Raw}, State: created.State}, updated)
+	if !updated.Diagnostics.HasError() || updated.Diagnostics[0].Summary() != "Record changed since plan" {
+		t.Errorf("expected the update to be rejected, got %v", updated.Diagnostics)
+	}
+	if name := org.Record("UserRole", data.Id.Value)["Name"]; name != "R&D" {
+		t.Errorf("expected the change made outside of Terraform to be kept, got %v", name)
+	}
+}
+
 func testAccResourceUserRole_basic(developerName string) string {
 	return fmt.Sprintf(`
 resource "salesforce_user_role" "test" {
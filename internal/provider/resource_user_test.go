@@ -4,13 +4,17 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/acctest"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/fakeorg"
 )
 
 func TestAccResourceUser_basic(t *testing.T) {
@@ -86,6 +90,107 @@ func TestAccResourceUser_update(t *testing.T) {
 	})
 }
 
+func TestUnitResourceUser_update(t *testing.T) {
+	t.Parallel()
+
+	org := fakeorg.New(t)
+	email := "test@fakeorg.example.com"
+	username := "test@fakeorg.example.com"
+	resource.UnitTest(t, resource.TestCase{
+		PreCheck:                 func() { testUnitPreCheck(t) },
+		ProtoV6ProviderFactories: providerFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUser_basic(email, username),
+			},
+			{
+				ResourceName:      "salesforce_user.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUser_full(email, username),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrPair("salesforce_user.test", "user_role_id", "salesforce_user_role.usertest", "id"),
+					resource.TestCheckResourceAttr("salesforce_user.test", "time_zone_sid_key", "America/Chicago"),
+				),
+			},
+			{
+				Config: testUnitProviderConfig(org) + testAccResourceUser_full_no_role(email, username),
+			},
+		},
+	})
+}
+
+func TestResourceUser_fakeOrg(t *testing.T) {
+	ctx := context.Background()
+	org := fakeorg.New(t)
+	schema, newResource := testFakeOrgResource(t, org, userType{})
+	license := org.Insert("UserLicense", map[string]interface{}{"Name": "Identity", "LicenseDefinitionKey": "PID_Identity"})
+	profile := org.Insert("Profile", map[string]interface{}{"Name": "Auditors", "UserLicenseId": license})
+
+	plan := tfsdk.Plan{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id":                  tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"last_modified_date":  tftypes.NewValue(tftypes.String, tftypes.UnknownValue),
+		"alias":               tftypes.NewValue(tftypes.String, "test"),
+		"email":               tftypes.NewValue(tftypes.String, "test@fakeorg.example.com"),
+		"last_name":           tftypes.NewValue(tftypes.String, "test"),
+		"username":            tftypes.NewValue(tftypes.String, "test@fakeorg.example.com"),
+		"profile_id":          tftypes.NewValue(tftypes.String, profile),
+		"email_encoding_key":  tftypes.NewValue(tftypes.String, "UTF-8"),
+		"language_locale_key": tftypes.NewValue(tftypes.String, "en_US"),
+		"locale_sid_key":      tftypes.NewValue(tftypes.String, "en_US"),
+		"time_zone_sid_key":   tftypes.NewValue(tftypes.String, "America/Chicago"),
+		"reset_password":      tftypes.NewValue(tftypes.Bool, true),
+	})}
+	created := &tfsdk.CreateResourceResponse{State: tfsdk.State{Schema: schema, Raw: tftypes.NewValue(schema.TerraformType(ctx), nil)}}
+	newResource().Create(ctx, tfsdk.CreateResourceRequest{Plan: plan}, created)
+	if created.Diagnostics.HasError() {
+		t.Fatal(created.Diagnostics)
+	}
+	var data userResourceData
+	if diags := created.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	record := org.Record("User", data.Id.Value)
+	if record == nil || record["Username"] != "test@fakeorg.example.com" {
+		t.Fatalf("expected the user to be created, got %v", record)
+	}
+	if resets := org.PasswordResets(data.Id.Value); resets != 1 {
+		t.Errorf("expected the password to be reset once on create, got %d resets", resets)
+	}
+
+	// a change made outside of Terraform is read back into state
+	org.Update("User", data.Id.Value, map[string]interface{}{"TimeZoneSidKey": "Europe/Paris"})
+	refreshed := &tfsdk.ReadResourceResponse{State: created.State}
+	newResource().Read(ctx, tfsdk.ReadResourceRequest{State: created.State}, refreshed)
+	if refreshed.Diagnostics.HasError() {
+		t.Fatal(refreshed.Diagnostics)
+	}
+	if diags := refreshed.State.Get(ctx, &data); diags.HasError() {
+		t.Fatal(diags)
+	}
+	if data.TimeZoneSidKey != "Europe/Paris" || !data.ResetPassword {
+		t.Errorf("expected the refreshed time zone with reset_password kept, got %#v", data)
+	}
+
+	// users cannot be deleted, destroy deactivates them
+	deleted := &tfsdk.DeleteResourceResponse{State: refreshed.State}
+	newResource().Delete(ctx, tfsdk.DeleteResourceRequest{State: refreshed.State}, deleted)
+	if deleted.Diagnostics.HasError() {
+		t.Fatal(deleted.Diagnostics)
+	}
+	if !deleted.State.Raw.IsNull() {
+		t.Error("expected the user to be removed from state")
+	}
+	if record := org.Record("User", data.Id.Value); record == nil || record["IsActive"] != false {
+		t.Errorf("expected the user to be deactivated, got %v", record)
+	}
+	if len(deleted.Diagnostics) != 1 || deleted.Diagnostics[0].Summary() != "Users cannot be deleted from salesforce" {
+		t.Errorf("expected a warning that the user was only deactivated, got %v", deleted.Diagnostics)
+	}
+}
+
 func testAccResourceUser_basic(email, username string) string {
 	return fmt.Sprintf(`
 data "salesforce_profile" "standard" {
@@ -0,0 +1,34 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the span of a resource operation, such as User.Create, the requests
+// sent to Salesforce during the operation are traced as its children
+func startSpan(ctx context.Context, sobjectType string, operation string) (context.Context, trace.Span) {
+	return telemetry.StartSpan(ctx, sobjectType+"."+operation, telemetry.SObjectAttributes(sobjectType, "")...)
+}
+
+// endSpan tags the span with the record ID, known only once the record was created or read,
+// and marks it as failed when the operation reported errors
+func endSpan(span trace.Span, id string, diags diag.Diagnostics) {
+	if id != "" {
+		span.SetAttributes(telemetry.RecordIdKey.String(id))
+	}
+	for _, d := range diags {
+		if d.Severity() == diag.SeverityError {
+			span.SetStatus(codes.Error, d.Summary())
+			break
+		}
+	}
+	span.End()
+}
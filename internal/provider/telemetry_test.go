@@ -0,0 +1,89 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestResourceSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	schema, diags := userType{}.GetSchema(ctx)
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	client := rest.NewClient(server.Client(), server.URL, "v55.0", rest.RetryPolicy{})
+	u := &userResource{Resource: Resource{
+		Client: client,
+		Batch:  rest.NewBatcher(client),
+		Data:   &userResourceData{},
+	}}
+	req := tfsdk.DeleteResourceRequest{State: tfsdk.State{Schema: schema, Raw: testObject(t, schema, map[string]tftypes.Value{
+		"id": tftypes.NewValue(tftypes.String, "005000000000001AAA"),
+	})}}
+	resp := &tfsdk.DeleteResourceResponse{State: req.State}
+	u.Delete(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatal(resp.Diagnostics)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	request, operation := spans[0], spans[1]
+	if operation.Name() != "User.Delete" {
+		t.Errorf("unexpected operation span %s", operation.Name())
+	}
+	if request.Name() != "HTTP PATCH" {
+		t.Errorf("unexpected request span %s", request.Name())
+	}
+	if request.Parent().SpanID() != operation.SpanContext().SpanID() {
+		t.Error("expected the request span to be a child of the operation span")
+	}
+	for _, span := range spans {
+		if got := spanAttribute(span, telemetry.SObjectTypeKey); got != "User" {
+			t.Errorf("%s: unexpected sobject type %q", span.Name(), got)
+		}
+		if got := spanAttribute(span, telemetry.RecordIdKey); got != "005000000000001AAA" {
+			t.Errorf("%s: unexpected record id %q", span.Name(), got)
+		}
+		if span.Status().Code == codes.Error {
+			t.Errorf("%s: unexpected error status %s", span.Name(), span.Status().Description)
+		}
+	}
+}
@@ -5,7 +5,8 @@ package provider
 
 import (
 	"context"
-	"regexp"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/rest"
 )
 
 type emptyDescriptions struct {
@@ -19,8 +20,6 @@ func (emptyDescriptions) MarkdownDescription(ctx context.Context) string {
 	return ""
 }
 
-var errorNotFoundRegexp = regexp.MustCompile("NOT_FOUND|does not exist")
-
 func isNotFoundError(err error) bool {
-	return errorNotFoundRegexp.MatchString(err.Error())
+	return rest.IsNotFound(err)
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -71,3 +72,17 @@ func (s stringInSlice) Validate(_ context.Context, req tfsdk.ValidateAttributeRe
 	}
 	resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid string", fmt.Sprintf("String must be one of: [%s]", strings.Join(s.slice, ", ")))
 }
+
+type duration struct {
+	emptyDescriptions
+}
+
+func (duration) Validate(_ context.Context, req tfsdk.ValidateAttributeRequest, resp *tfsdk.ValidateAttributeResponse) {
+	attr := req.AttributeConfig.(types.String)
+	if attr.Unknown || attr.Null {
+		return
+	}
+	if _, err := time.ParseDuration(attr.Value); err != nil {
+		resp.Diagnostics.AddAttributeError(req.AttributePath, "Invalid duration", fmt.Sprintf("Duration must be a number with a unit such as 30s or 2m: %s", err))
+	}
+}
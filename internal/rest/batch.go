@@ -0,0 +1,471 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"github.com/nimajalali/go-force/forcejson"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxBatchSize is the maximum number of records of a single /composite/sobjects request
+const MaxBatchSize = 200
+
+// batchWindow is how long a batch waits for more records before it is sent, Terraform starts
+// the operations of a graph walk at nearly the same time so a short window is enough
+var batchWindow = 50 * time.Millisecond
+
+// Batcher gathers concurrent writes of the same SObject type into /composite/sobjects requests
+// and concurrent reads into a single SOQL query, every caller still receives the result and
+// error of its own record
+type Batcher struct {
+	client *Client
+
+	mu      sync.Mutex
+	pending map[batchKey]*batch
+}
+
+type batchKey struct {
+	method  string
+	sobject string
+}
+
+type batch struct {
+	records []*batchRecord
+}
+
+// batchRecord is the record of a single operation, ctx is the context of that operation
+type batchRecord struct {
+	ctx     context.Context
+	id      string
+	sobject SObject
+	err     error
+	done    chan struct{}
+}
+
+// compositeResult is the outcome of a single record of a /composite/sobjects request
+type compositeResult struct {
+	Id      string `force:"id,omitempty"`
+	Success bool   `force:"success,omitempty"`
+	Errors  []struct {
+		StatusCode string   `force:"statusCode,omitempty"`
+		Message    string   `force:"message,omitempty"`
+		Fields     []string `force:"fields,omitempty"`
+	} `force:"errors,omitempty"`
+}
+
+func (r compositeResult) err() error {
+	if r.Success {
+		return nil
+	}
+	apiErrors := &Errors{StatusCode: http.StatusBadRequest}
+	for _, e := range r.Errors {
+		apiErrors.Errors = append(apiErrors.Errors, &Error{ErrorCode: e.StatusCode, Message: e.Message, Fields: e.Fields})
+	}
+	if len(apiErrors.Errors) == 0 {
+		apiErrors.Errors = []*Error{{Message: "the record was not saved"}}
+	}
+	return apiErrors
+}
+
+// retryable reports whether the record failed with errors that are safe to retry, there is no
+// Retry-After for a single record
+func (r compositeResult) retryable() bool {
+	if r.Success {
+		return false
+	}
+	apiErrors := r.err().(*Errors)
+	return retryableErrors(apiErrors.Errors, false)
+}
+
+// NewBatcher returns a batcher sending the requests through client
+func NewBatcher(client *Client) *Batcher {
+	return &Batcher{
+		client:  client,
+		pending: map[batchKey]*batch{},
+	}
+}
+
+// Get reads the record with the given id into out, the fields are those mapped by the force
+// tags of out. A record that doesn't exist is reported as a NOT_FOUND error, as by Client.Get
+func (b *Batcher) Get(ctx context.Context, id string, out SObject) error {
+	_, err := b.submit(ctx, http.MethodGet, id, out)
+	return err
+}
+
+// Insert creates a record and returns its id
+func (b *Batcher) Insert(ctx context.Context, in SObject) (string, error) {
+	record, err := b.submit(ctx, http.MethodPost, "", in)
+	if err != nil {
+		return "", err
+	}
+	return record.id, nil
+}
+
+// Update patches the record with the given id with the fields of in
+func (b *Batcher) Update(ctx context.Context, id string, in SObject) error {
+	_, err := b.submit(ctx, http.MethodPatch, id, in)
+	return err
+}
+
+// Delete deletes the record with the given id, sobject is only used for its ApiName
+func (b *Batcher) Delete(ctx context.Context, id string, sobject SObject) error {
+	_, err := b.submit(ctx, http.MethodDelete, id, sobject)
+	return err
+}
+
+// submit adds the record to the pending batch of its SObject type and method and waits for
+// its result. A full batch is sent right away, otherwise when the batch window expires
+func (b *Batcher) submit(ctx context.Context, method string, id string, in SObject) (*batchRecord, error) {
+	record := &batchRecord{ctx: ctx, id: id, sobject: in, done: make(chan struct{})}
+	key := batchKey{method: method, sobject: in.ApiName()}
+
+	b.mu.Lock()
+	pending, ok := b.pending[key]
+	if !ok {
+		pending = &batch{}
+		b.pending[key] = pending
+		time.AfterFunc(batchWindow, func() { b.flush(key, pending) })
+	}
+	pending.records = append(pending.records, record)
+	if len(pending.records) == MaxBatchSize {
+		delete(b.pending, key)
+		go b.send(key, pending)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-record.done:
+		return record, record.err
+	case <-ctx.Done():
+	}
+
+	// a canceled record is withdrawn from its batch while the batch is pending, once the batch
+	// was sent the record may be applied, such as a record created that would never be in the
+	// state, so its result is awaited
+	b.mu.Lock()
+	if b.pending[key] == pending {
+		for i, r := range pending.records {
+			if r == record {
+				pending.records = append(pending.records[:i], pending.records[i+1:]...)
+				break
+			}
+		}
+		if len(pending.records) == 0 {
+			delete(b.pending, key)
+		}
+		b.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	b.mu.Unlock()
+	<-record.done
+	return record, record.err
+}
+
+func (b *Batcher) flush(key batchKey, pending *batch) {
+	b.mu.Lock()
+	if b.pending[key] != pending {
+		// the batch was full and has already been sent
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	b.mu.Unlock()
+	b.send(key, pending)
+}
+
+func (b *Batcher) send(key batchKey, pending *batch) {
+	defer func() {
+		for _, record := range pending.records {
+			close(record.done)
+		}
+	}()
+
+	// a lone record is sent as a regular request, there is nothing to gain from a collection,
+	// and so are records that can't be queried field by field. Each is sent with the context of
+	// its own operation
+	fields, queryable := queryFields(pending.records[0].sobject)
+	if len(pending.records) == 1 || (key.method == http.MethodGet && !queryable) {
+		for _, record := range pending.records {
+			switch key.method {
+			case http.MethodGet:
+				record.err = b.client.Get(record.ctx, record.id, nil, record.sobject)
+			case http.MethodPost:
+				record.id, record.err = b.client.Insert(record.ctx, record.sobject)
+			case http.MethodPatch:
+				record.err = b.client.Update(record.ctx, record.id, record.sobject)
+			case http.MethodDelete:
+				record.err = b.client.Delete(record.ctx, record.id, record.sobject)
+			}
+		}
+		return
+	}
+
+	sortRecords(pending.records)
+
+	// the batch serves several operations, canceling one of them must not fail the others. It is
+	// traced in a span of its own linked to the span of every operation
+	links := make([]trace.Link, len(pending.records))
+	for i, record := range pending.records {
+		links[i] = trace.LinkFromContext(record.ctx)
+	}
+	ctx, cancel := batchContext(pending.records)
+	defer cancel()
+	ctx, span := telemetry.StartLinkedSpan(ctx, key.sobject+".Batch", links,
+		semconv.HTTPMethod(key.method), telemetry.SObjectTypeKey.String(key.sobject), telemetry.BatchSizeKey.Int(len(pending.records)))
+	defer span.End()
+
+	if key.method == http.MethodGet {
+		b.sendQuery(ctx, key.sobject, fields, pending.records)
+		return
+	}
+
+	// the records of a collection fail on their own with a 200 status, those rejected with a
+	// transient error such as UNABLE_TO_LOCK_ROW are sent again with the backoff of the client
+	records := pending.records
+	for attempt := 0; ; attempt++ {
+		results, err := b.sendComposite(ctx, key.method, records)
+		var retry []*batchRecord
+		for i, record := range records {
+			switch {
+			case err != nil:
+				record.err = err
+			case i >= len(results):
+				record.err = fmt.Errorf("Salesforce did not return a result for the %s record %d of the batch", key.sobject, i)
+			default:
+				record.err = results[i].err()
+				if record.err == nil && key.method == http.MethodPost {
+					record.id = results[i].Id
+				}
+				if results[i].retryable() {
+					retry = append(retry, record)
+				}
+			}
+		}
+		if len(retry) == 0 || attempt >= b.client.retry.MaxRetries {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			for _, record := range retry {
+				record.err = fmt.Errorf("%w, gave up retrying: %v", record.err, ctx.Err())
+			}
+			return
+		case <-time.After(b.client.retry.backoff(attempt)):
+		}
+		records = retry
+	}
+}
+
+// sendComposite sends the records in a single request, allOrNone is false so each record
+// succeeds or fails on its own and the results are in the order of the records
+func (b *Batcher) sendComposite(ctx context.Context, method string, records []*batchRecord) ([]compositeResult, error) {
+	var results []compositeResult
+	path := b.client.dataPath("composite", "sobjects")
+
+	if method == http.MethodDelete {
+		ids := make([]string, len(records))
+		for i, record := range records {
+			ids[i] = record.id
+		}
+		params := url.Values{"ids": {strings.Join(ids, ",")}, "allOrNone": {"false"}}
+		if err := b.client.Do(ctx, method, path, params, nil, &results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	payload := struct {
+		AllOrNone bool              `force:"allOrNone"`
+		Records   []json.RawMessage `force:"records"`
+	}{}
+	for _, record := range records {
+		body, err := collectionRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		payload.Records = append(payload.Records, body)
+	}
+	if err := b.client.Do(ctx, method, path, nil, payload, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// sendQuery reads the records with a single SOQL query, records missing from the result don't
+// exist or were deleted
+func (b *Batcher) sendQuery(ctx context.Context, sobject string, fields []string, records []*batchRecord) {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = QuoteString(record.id)
+	}
+	query := BuildQuery(strings.Join(fields, ", "), sobject, []string{fmt.Sprintf("Id IN (%s)", strings.Join(ids, ", "))})
+
+	var resp struct {
+		BaseQuery
+		Records []forcejson.RawMessage `force:"records"`
+	}
+	if err := b.client.Query(ctx, query, &resp); err != nil {
+		for _, record := range records {
+			record.err = err
+		}
+		return
+	}
+
+	rows := make(map[string]forcejson.RawMessage, len(resp.Records))
+	for _, row := range resp.Records {
+		var key struct {
+			Id string `force:"Id"`
+		}
+		if err := forcejson.Unmarshal(row, &key); err == nil {
+			rows[shortId(key.Id)] = row
+		}
+	}
+	for _, record := range records {
+		row, ok := rows[shortId(record.id)]
+		if !ok {
+			record.err = &Errors{StatusCode: http.StatusNotFound, Errors: []*Error{{
+				ErrorCode: "NOT_FOUND",
+				Message:   fmt.Sprintf("The requested %s %s does not exist", sobject, record.id),
+			}}}
+			continue
+		}
+		if err := forcejson.Unmarshal(row, record.sobject); err != nil {
+			record.err = fmt.Errorf("Unable to unmarshal %s %s: %v", sobject, record.id, err)
+		}
+	}
+}
+
+// sortRecords orders the records by id, or by their fields for inserts, so the same operations
+// always make the same request whatever the order they were submitted in
+func sortRecords(records []*batchRecord) {
+	keys := make(map[*batchRecord]string, len(records))
+	for _, record := range records {
+		keys[record] = record.id
+		if record.id == "" {
+			b, _ := forcejson.Marshal(record.sobject)
+			keys[record] = string(b)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return keys[records[i]] < keys[records[j]]
+	})
+}
+
+// detachedContext carries the values of a context, such as the logger of the operation, without
+// its cancellation and deadline
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+// batchContext returns the context of a batch, it carries the values of the context of the first
+// record and is canceled once the contexts of all the records are, such as when Terraform stops
+// the provider
+func batchContext(records []*batchRecord) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(detach(records[0].ctx))
+	go func() {
+		for _, record := range records {
+			select {
+			case <-record.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// shortId returns the case sensitive 15 character form of an id, queries return the 18
+// character form while ids in state may use either
+func shortId(id string) string {
+	if len(id) == 18 {
+		return id[:15]
+	}
+	return id
+}
+
+// queryFields returns the fields mapped by the force tags of a struct, the Id is always
+// selected. Records that aren't structs, such as maps of all fields, can't be queried
+func queryFields(sobject SObject) ([]string, bool) {
+	t := reflect.TypeOf(sobject)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	fields := []string{"Id"}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("force"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if !strings.EqualFold(name, "Id") {
+			fields = append(fields, name)
+		}
+	}
+	return fields, true
+}
+
+// collectionRecord encodes a record of a collection, which carries its type in attributes and
+// the id of the record to update next to its fields
+func collectionRecord(record *batchRecord) (json.RawMessage, error) {
+	b, err := forcejson.Marshal(record.sobject)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	attributes, err := json.Marshal(map[string]string{"type": record.sobject.ApiName()})
+	if err != nil {
+		return nil, err
+	}
+	fields["attributes"] = attributes
+	if record.id != "" {
+		id, err := json.Marshal(record.id)
+		if err != nil {
+			return nil, err
+		}
+		fields["id"] = id
+	}
+	return json.Marshal(fields)
+}
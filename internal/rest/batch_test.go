@@ -0,0 +1,491 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestBatcher_insert(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/services/data/v55.0/composite/sobjects" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			AllOrNone bool                     `json:"allOrNone"`
+			Records   []map[string]interface{} `json:"records"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		if body.AllOrNone {
+			t.Error("expected allOrNone to be false")
+		}
+		mu.Lock()
+		batches = append(batches, body.Records)
+		mu.Unlock()
+
+		results := make([]map[string]interface{}, len(body.Records))
+		for i, record := range body.Records {
+			if record["LastName"] == "Duplicate" {
+				results[i] = map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"statusCode": "DUPLICATE_USERNAME", "message": "Duplicate Username", "fields": []string{}}}}
+				continue
+			}
+			results[i] = map[string]interface{}{"success": true, "id": fmt.Sprintf("005%015d", i), "errors": []interface{}{}}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	names := []string{"Doe", "Duplicate", "Roe"}
+	ids := make([]string, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			ids[i], errs[i] = batcher.Insert(context.Background(), testUser{LastName: name})
+		}(i, name)
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != len(names) {
+		t.Fatalf("expected a single batch of %d records, got %v", len(names), batches)
+	}
+	for _, record := range batches[0] {
+		if attributes, _ := record["attributes"].(map[string]interface{}); attributes["type"] != "User" {
+			t.Errorf("expected the record type in attributes, got %v", record)
+		}
+	}
+	// the order of the records depends on the scheduling of the goroutines
+	for i, name := range names {
+		if name == "Duplicate" {
+			apiErrors, ok := errs[i].(*Errors)
+			if !ok || !apiErrors.HasCode("DUPLICATE_USERNAME") {
+				t.Errorf("expected DUPLICATE_USERNAME error, got %#v", errs[i])
+			} else if fields := apiErrors.Errors[0].FieldNames(); len(fields) != 1 || fields[0] != "Username" {
+				t.Errorf("unexpected fields %v", fields)
+			}
+			continue
+		}
+		if errs[i] != nil || !strings.HasPrefix(ids[i], "005") {
+			t.Errorf("%s: unexpected result %q %v", name, ids[i], errs[i])
+		}
+	}
+}
+
+func TestBatcher_retryLockedRecords(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	var mu sync.Mutex
+	var batches [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []map[string]interface{} `json:"records"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		var names []string
+		for _, record := range body.Records {
+			names = append(names, record["LastName"].(string))
+		}
+		batches = append(batches, names)
+
+		// the row of Locked is released after the first attempt, the one of Stuck never is
+		results := make([]map[string]interface{}, len(body.Records))
+		for i, name := range names {
+			if name == "Stuck" || (name == "Locked" && len(batches) == 1) {
+				results[i] = map[string]interface{}{"success": false, "errors": []map[string]interface{}{{"statusCode": "UNABLE_TO_LOCK_ROW", "message": "unable to obtain exclusive access to this record"}}}
+				continue
+			}
+			results[i] = map[string]interface{}{"success": true, "id": fmt.Sprintf("005%015d", len(batches)*10+i)}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{MaxRetries: 2}))
+	names := []string{"Doe", "Locked", "Stuck"}
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			_, errs[i] = batcher.Insert(context.Background(), testUser{LastName: name})
+		}(i, name)
+	}
+	wg.Wait()
+
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("expected Doe and Locked to be inserted, got %v and %v", errs[0], errs[1])
+	}
+	if apiErrors, ok := errs[2].(*Errors); !ok || !apiErrors.HasCode("UNABLE_TO_LOCK_ROW") {
+		t.Errorf("expected UNABLE_TO_LOCK_ROW once the retries are exhausted, got %v", errs[2])
+	}
+	// only the locked records are sent again
+	if len(batches) != 3 || len(batches[0]) != 3 || len(batches[1]) != 2 || len(batches[2]) != 1 || batches[2][0] != "Stuck" {
+		t.Errorf("expected the locked records to be retried twice, got %v", batches)
+	}
+}
+
+func TestBatcher_canceledOperation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var sent int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Records []json.RawMessage `json:"records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		atomic.AddInt32(&sent, int32(len(body.Records)))
+		results := make([]map[string]interface{}, len(body.Records))
+		for i := range results {
+			results[i] = map[string]interface{}{"id": fmt.Sprintf("005%015d", i), "success": true}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	// the operation opening the batch is canceled before the batch is sent, the batch is sent
+	// for the other operations
+	canceled, cancel := context.WithCancel(context.Background())
+	errs := make([]error, 3)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := context.Background()
+			if i == 0 {
+				ctx = canceled
+			} else {
+				time.Sleep(batchWindow / 5)
+			}
+			ctx, span := telemetry.StartSpan(ctx, "User.Create")
+			defer span.End()
+			_, errs[i] = batcher.Insert(ctx, testUser{LastName: "Doe"})
+		}(i)
+	}
+	time.Sleep(batchWindow / 2)
+	cancel()
+	wg.Wait()
+
+	if !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("expected the canceled operation to fail, got %v", errs[0])
+	}
+	if errs[1] != nil || errs[2] != nil {
+		t.Errorf("expected the other operations to succeed, got %v and %v", errs[1], errs[2])
+	}
+	// the canceled record is withdrawn from the batch, it would never be in the state
+	if sent := atomic.LoadInt32(&sent); sent != 2 {
+		t.Errorf("expected only the records of the other operations to be sent, got %d records", sent)
+	}
+
+	var batch sdktrace.ReadOnlySpan
+	operations := map[trace.SpanID]bool{}
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "User.Batch":
+			batch = span
+		case "User.Create":
+			operations[span.SpanContext().SpanID()] = true
+		}
+	}
+	if batch == nil {
+		t.Fatal("expected a span for the batch")
+	}
+	if batch.Parent().IsValid() {
+		t.Errorf("expected the batch to be a root span, got parent %v", batch.Parent())
+	}
+	if len(batch.Links()) != 2 {
+		t.Fatalf("expected the batch to be linked to 2 operations, got %v", batch.Links())
+	}
+	for _, link := range batch.Links() {
+		if !operations[link.SpanContext.SpanID()] {
+			t.Errorf("unexpected link %v", link.SpanContext)
+		}
+	}
+}
+
+func TestBatcher_canceledAfterSend(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": "005000000000001AAA", "success": true},
+			{"id": "005000000000002AAA", "success": true},
+		})
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	canceled, cancel := context.WithCancel(context.Background())
+	ids := make([]string, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i, ctx := range []context.Context{canceled, context.Background()} {
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			ids[i], errs[i] = batcher.Insert(ctx, testUser{LastName: "Doe"})
+		}(i, ctx)
+	}
+	// the operation is canceled while Salesforce creates its record
+	<-received
+	cancel()
+	time.Sleep(batchWindow / 5)
+	close(release)
+	wg.Wait()
+
+	if errs[0] != nil || ids[0] == "" {
+		t.Errorf("expected the canceled operation to get the id of its created record, got %q and %v", ids[0], errs[0])
+	}
+	if errs[1] != nil || ids[1] == "" {
+		t.Errorf("expected the other operation to succeed, got %q and %v", ids[1], errs[1])
+	}
+}
+
+func TestBatcher_deleteAndUpdate(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		switch r.Method {
+		case http.MethodDelete:
+			if r.URL.Query().Get("allOrNone") != "false" {
+				t.Errorf("expected allOrNone=false, got %s", r.URL.RawQuery)
+			}
+			var results []map[string]interface{}
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				if id == "005000000000002AAA" {
+					results = append(results, map[string]interface{}{"id": id, "success": false, "errors": []map[string]interface{}{{"statusCode": "ENTITY_IS_DELETED", "message": "entity is deleted"}}})
+					continue
+				}
+				results = append(results, map[string]interface{}{"id": id, "success": true})
+			}
+			_ = json.NewEncoder(w).Encode(results)
+		case http.MethodPatch:
+			// a single update is sent to the record itself
+			if r.URL.Path != "/services/data/v55.0/sobjects/User/005000000000003AAA" {
+				t.Errorf("unexpected update %s", r.URL.Path)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	var deleted, missing, updated error
+	wg.Add(3)
+	go func() { defer wg.Done(); deleted = batcher.Delete(ctx, "005000000000001AAA", testUser{}) }()
+	go func() { defer wg.Done(); missing = batcher.Delete(ctx, "005000000000002AAA", testUser{}) }()
+	go func() {
+		defer wg.Done()
+		isActive := false
+		updated = batcher.Update(ctx, "005000000000003AAA", testUser{IsActive: &isActive})
+	}()
+	wg.Wait()
+
+	if deleted != nil || updated != nil {
+		t.Errorf("unexpected errors %v %v", deleted, updated)
+	}
+	if !IsNotFound(missing) {
+		t.Errorf("expected a not found error, got %v", missing)
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected a delete and an update request, got %v", requests)
+	}
+}
+
+func TestBatcher_maxBatchSize(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/data/v55.0/sobjects/User" {
+			// a lone record is sent to the sobject itself
+			_, _ = w.Write([]byte(`{"id":"005000000000001AAA","success":true}`))
+			mu.Lock()
+			sizes = append(sizes, 1)
+			mu.Unlock()
+			return
+		}
+		var body struct {
+			Records []json.RawMessage `json:"records"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		sizes = append(sizes, len(body.Records))
+		mu.Unlock()
+		results := make([]map[string]interface{}, len(body.Records))
+		for i := range results {
+			results[i] = map[string]interface{}{"id": "005000000000001AAA", "success": true}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	var wg sync.WaitGroup
+	for i := 0; i < MaxBatchSize+1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := batcher.Insert(context.Background(), testUser{LastName: "Doe"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for _, size := range sizes {
+		if size > MaxBatchSize {
+			t.Errorf("batch of %d records exceeds the maximum", size)
+		}
+		total += size
+	}
+	if total != MaxBatchSize+1 || len(sizes) != 2 {
+		t.Errorf("expected 2 requests for %d records, got %v", MaxBatchSize+1, sizes)
+	}
+}
+
+func TestBatcher_get(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/data/v55.0/query" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		queries = append(queries, r.URL.Query().Get("q"))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"totalSize":2,"done":true,"records":[
+			{"attributes":{"type":"User"},"Id":"005000000000001AAA","LastName":"Doe","IsActive":true},
+			{"attributes":{"type":"User"},"Id":"005000000000003AAA","LastName":"Roe","IsActive":false}
+		]}`))
+	}))
+	defer server.Close()
+
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}))
+	// the 15 character id of the third user matches the 18 character id returned by the query
+	ids := []string{"005000000000001AAA", "005000000000002AAA", "005000000000003"}
+	users := make([]testUser, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			errs[i] = batcher.Get(context.Background(), id, &users[i])
+		}(i, id)
+	}
+	wg.Wait()
+
+	if len(queries) != 1 {
+		t.Fatalf("expected a single query, got %v", queries)
+	}
+	// the ids are sorted whatever the order the reads were submitted in
+	if queries[0] != "SELECT Id, LastName, IsActive FROM User WHERE Id IN ('005000000000001AAA', '005000000000002AAA', '005000000000003')" {
+		t.Errorf("unexpected query %s", queries[0])
+	}
+	if errs[0] != nil || users[0].LastName != "Doe" || users[0].IsActive == nil || !*users[0].IsActive {
+		t.Errorf("unexpected first user %#v %v", users[0], errs[0])
+	}
+	if !IsNotFound(errs[1]) {
+		t.Errorf("expected the missing user to be not found, got %v", errs[1])
+	}
+	if errs[2] != nil || users[2].LastName != "Roe" || users[2].IsActive == nil || *users[2].IsActive {
+		t.Errorf("unexpected third user %#v %v", users[2], errs[2])
+	}
+}
+
+func TestBatcher_canceledRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"success": false, "errors": []map[string]interface{}{{"statusCode": "UNABLE_TO_LOCK_ROW", "message": "unable to obtain exclusive access to this record"}}},
+			{"success": false, "errors": []map[string]interface{}{{"statusCode": "UNABLE_TO_LOCK_ROW", "message": "unable to obtain exclusive access to this record"}}},
+		})
+	}))
+	defer server.Close()
+
+	// the backoff is far longer than the test, it must be cut short once the operations are canceled
+	batcher := NewBatcher(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{MaxRetries: 5, MaxRetryWait: time.Hour}))
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = batcher.Insert(ctx, testUser{LastName: fmt.Sprintf("Doe%d", i)})
+		}(i)
+	}
+	time.Sleep(2 * batchWindow)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the retry to stop once the operations are canceled")
+	}
+	for i, err := range errs {
+		var apiErrors *Errors
+		if !errors.As(err, &apiErrors) || !apiErrors.HasCode("UNABLE_TO_LOCK_ROW") || !strings.Contains(err.Error(), "gave up retrying: context canceled") {
+			t.Errorf("expected operation %d to give up retrying UNABLE_TO_LOCK_ROW, got %v", i, err)
+		}
+	}
+}
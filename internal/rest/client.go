@@ -0,0 +1,231 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package rest is a context aware client for the Salesforce REST API, every request
+// is bound to the context of the Terraform operation so cancellation and deadlines
+// reach the wire
+package rest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"github.com/nimajalali/go-force/forcejson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+const (
+	userAgent   = "terraform-provider-salesforce"
+	contentType = "application/json"
+)
+
+// Client sends requests to the REST API of a single org, authorization is expected
+// to be handled by the transport of the http.Client
+type Client struct {
+	httpClient  *http.Client
+	instanceUrl string
+	apiVersion  string
+	retry       RetryPolicy
+
+	mu          sync.Mutex
+	identityUrl string
+	userId      string
+}
+
+// NewClient returns a client for the org at instanceUrl, apiVersion is in the format MAJOR.MINOR
+func NewClient(httpClient *http.Client, instanceUrl string, apiVersion string, retry RetryPolicy) *Client {
+	return &Client{
+		httpClient:  httpClient,
+		instanceUrl: strings.TrimSuffix(instanceUrl, "/"),
+		apiVersion:  strings.TrimPrefix(apiVersion, "v"),
+		retry:       retry,
+	}
+}
+
+func (c *Client) InstanceUrl() string {
+	return c.instanceUrl
+}
+
+func (c *Client) ApiVersion() string {
+	return c.apiVersion
+}
+
+// SetIdentityUrl records the identity URL returned by the token exchange, such as
+// https://login.salesforce.com/id/00D000000000001AAA/005000000000001AAA
+func (c *Client) SetIdentityUrl(identityUrl string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.identityUrl = identityUrl
+}
+
+// UserId returns the ID of the authenticated user, it is the last element of the identity URL
+// or, for access tokens obtained outside of the provider, is read from the userinfo endpoint
+func (c *Client) UserId(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userId != "" {
+		return c.userId, nil
+	}
+
+	if u, err := url.Parse(c.identityUrl); err == nil && c.identityUrl != "" {
+		if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) == 3 && parts[0] == "id" {
+			c.userId = parts[2]
+			return c.userId, nil
+		}
+	}
+	var userInfo struct {
+		UserId string `force:"user_id"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/services/oauth2/userinfo", nil, nil, &userInfo); err != nil {
+		return "", err
+	}
+	if userInfo.UserId == "" {
+		return "", fmt.Errorf("Salesforce did not return the id of the authenticated user")
+	}
+	c.userId = userInfo.UserId
+	return c.userId, nil
+}
+
+// dataPath returns the path of a resource of the versioned REST API, such as /services/data/v53.0/sobjects
+func (c *Client) dataPath(elem ...string) string {
+	return fmt.Sprintf("/services/data/v%s/%s", c.apiVersion, strings.Join(elem, "/"))
+}
+
+// Do sends a request to path, relative to the instance url. The payload is encoded and the response
+// decoded with forcejson so the force struct tags of the SObjects apply, out may be nil. Requests
+// failing with a transient error are retried according to the retry policy of the client
+func (c *Client) Do(ctx context.Context, method string, path string, params url.Values, payload interface{}, out interface{}) error {
+	return c.do(ctx, method, path, params, nil, payload, out)
+}
+
+// do is Do with additional request headers, such as conditional headers
+func (c *Client) do(ctx context.Context, method string, path string, params url.Values, header http.Header, payload interface{}, out interface{}) error {
+	uri := c.instanceUrl + path
+	if len(params) != 0 {
+		uri += "?" + params.Encode()
+	}
+
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = forcejson.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("Error marshaling encoded payload: %v", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, respBytes, err := c.send(ctx, method, path, uri, header, body)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode >= http.StatusBadRequest {
+			err := responseError(method, path, resp, respBytes)
+			wait, retry := c.retry.wait(attempt, method, resp, err)
+			if !retry {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w, gave up retrying: %v", err, ctx.Err())
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		if out == nil || resp.StatusCode == http.StatusNoContent || len(respBytes) == 0 {
+			return nil
+		}
+		if err := forcejson.Unmarshal(respBytes, out); err != nil {
+			return fmt.Errorf("Unable to unmarshal response to object: %v", err)
+		}
+		return nil
+	}
+}
+
+// send performs a single attempt of a request and reads the whole response, each attempt is traced in its own span
+func (c *Client) send(ctx context.Context, method string, path string, uri string, header http.Header, body []byte) (resp *http.Response, respBytes []byte, err error) {
+	attributes := []attribute.KeyValue{semconv.HTTPMethod(method), semconv.HTTPTarget(path)}
+	if sobjectType, id := sobjectFromPath(path); sobjectType != "" {
+		attributes = append(attributes, telemetry.SObjectAttributes(sobjectType, id)...)
+	}
+	ctx, span := telemetry.StartSpan(ctx, "HTTP "+method, attributes...)
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if resp.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+		span.End()
+	}()
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reqBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error creating %v request: %v", method, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error sending %v request: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	respBytes, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error reading response bytes: %w", err)
+	}
+	return resp, respBytes, nil
+}
+
+// sobjectFromPath extracts the SObject type and record ID from paths such as
+// /services/data/v55.0/sobjects/User/005000000000001AAA/password
+func sobjectFromPath(path string) (string, string) {
+	_, tail, found := strings.Cut(path, "/sobjects/")
+	if !found {
+		return "", ""
+	}
+	parts := strings.Split(tail, "/")
+	if len(parts) < 2 || parts[1] == "describe" {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// responseError parses the errors returned by the API, when the body isn't a list of errors the
+// raw body is returned as the message of a single error without code
+func responseError(method string, path string, resp *http.Response, respBytes []byte) error {
+	apiErrors := &Errors{StatusCode: resp.StatusCode}
+	if err := forcejson.Unmarshal(respBytes, &apiErrors.Errors); err == nil && len(apiErrors.Errors) > 0 {
+		return apiErrors
+	}
+	apiErrors.Errors = []*Error{{
+		ErrorCode: strings.ReplaceAll(strings.ToUpper(http.StatusText(resp.StatusCode)), " ", "_"),
+		Message:   fmt.Sprintf("%s %s returned %s: %s", method, path, resp.Status, respBytes),
+	}}
+	return apiErrors
+}
@@ -0,0 +1,263 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testUser struct {
+	LastName string  `force:",omitempty"`
+	IsActive *bool   `force:",omitempty"`
+	Id       *string `force:",omitempty"`
+}
+
+func (testUser) ApiName() string {
+	return "User"
+}
+
+func (testUser) ExternalIdApiName() string {
+	return ""
+}
+
+func TestClient_CRUD(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		query  string
+		body   string
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.RawQuery, string(body)})
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":"005000000000001AAA","success":true,"errors":[]}`))
+		case http.MethodGet:
+			if r.URL.Path == "/services/data/v55.0/sobjects/User/005000000000002AAA" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"attributes":{"type":"User"},"Id":"005000000000001AAA","LastName":"Doe","IsActive":true}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL+"/", "v55.0", RetryPolicy{})
+	ctx := context.Background()
+
+	id, err := client.Insert(ctx, testUser{LastName: "Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "005000000000001AAA" {
+		t.Errorf("unexpected id %s", id)
+	}
+
+	var user testUser
+	if err := client.Get(ctx, id, []string{"Id", "LastName"}, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.LastName != "Doe" || user.IsActive == nil || !*user.IsActive {
+		t.Errorf("unexpected user %#v", user)
+	}
+
+	isActive := false
+	if err := client.Update(ctx, id, testUser{IsActive: &isActive}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Delete(ctx, id, testUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Get(ctx, "005000000000002AAA", nil, &user)
+	var apiErrors *Errors
+	if !errors.As(err, &apiErrors) || apiErrors.Errors[0].ErrorCode != "NOT_FOUND" || !IsNotFound(err) {
+		t.Errorf("expected NOT_FOUND error, got %#v", err)
+	}
+
+	expected := []request{
+		{http.MethodPost, "/services/data/v55.0/sobjects/User", "", `{"LastName":"Doe"}`},
+		{http.MethodGet, "/services/data/v55.0/sobjects/User/005000000000001AAA", "fields=Id%2CLastName", ""},
+		{http.MethodPatch, "/services/data/v55.0/sobjects/User/005000000000001AAA", "", `{"IsActive":false}`},
+		{http.MethodDelete, "/services/data/v55.0/sobjects/User/005000000000001AAA", "", ""},
+		{http.MethodGet, "/services/data/v55.0/sobjects/User/005000000000002AAA", "", ""},
+	}
+	if len(requests) != len(expected) {
+		t.Fatalf("expected %d requests, got %#v", len(expected), requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("request %d: expected %#v, got %#v", i, expected[i], requests[i])
+		}
+	}
+}
+
+func TestClient_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != `SELECT Id, Name FROM Profile WHERE Name = 'O\'Brien'` {
+			t.Errorf("unexpected query %s", q)
+		}
+		_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"00e000000000001AAA","Name":"O'Brien"}]}`))
+	}))
+	defer server.Close()
+
+	var resp struct {
+		BaseQuery
+		Records []struct {
+			Id   string
+			Name string
+		}
+	}
+	client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	if err := client.Query(context.Background(), BuildQuery("Id, Name", "Profile", []string{"Name = " + QuoteString("O'Brien")}), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Done || resp.TotalSize != 1 || len(resp.Records) != 1 || resp.Records[0].Name != "O'Brien" {
+		t.Errorf("unexpected response %#v", resp)
+	}
+}
+
+func TestClient_canceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	err := client.Get(ctx, "005000000000001AAA", nil, &testUser{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestClient_spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "v55.0", RetryPolicy{})
+	if err := client.Get(context.Background(), "005000000000001AAA", nil, &testUser{}); !IsNotFound(err) {
+		t.Fatalf("expected NOT_FOUND error, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	attributes := map[attribute.Key]string{}
+	for _, kv := range spans[0].Attributes() {
+		attributes[kv.Key] = kv.Value.Emit()
+	}
+	expected := map[attribute.Key]string{
+		telemetry.SObjectTypeKey: "User",
+		telemetry.RecordIdKey:    "005000000000001AAA",
+		"http.method":            "GET",
+		"http.status_code":       "404",
+	}
+	for key, value := range expected {
+		if attributes[key] != value {
+			t.Errorf("expected %s=%s, got %q", key, value, attributes[key])
+		}
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[0].Status())
+	}
+}
+
+func TestSObjectFromPath(t *testing.T) {
+	cases := []struct {
+		path, sobjectType, id string
+	}{
+		{"/services/data/v55.0/sobjects/User", "User", ""},
+		{"/services/data/v55.0/sobjects/User/describe", "User", ""},
+		{"/services/data/v55.0/sobjects/User/005000000000001AAA/password", "User", "005000000000001AAA"},
+		{"/services/data/v55.0/query", "", ""},
+	}
+	for _, c := range cases {
+		sobjectType, id := sobjectFromPath(c.path)
+		if sobjectType != c.sobjectType || id != c.id {
+			t.Errorf("%s: got %q %q", c.path, sobjectType, id)
+		}
+	}
+}
+
+func TestClient_UpdateIfUnmodifiedSince(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if since := r.Header.Get("If-Unmodified-Since"); since != "Wed, 01 Jun 2022 12:34:56 GMT" {
+			t.Errorf("unexpected If-Unmodified-Since %q", since)
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	since := time.Date(2022, 6, 1, 14, 34, 56, 0, time.FixedZone("CEST", 2*60*60))
+	err := client.UpdateIfUnmodifiedSince(context.Background(), "005000000000001AAA", testUser{LastName: "Doe"}, since)
+	if !IsPreconditionFailed(err) {
+		t.Errorf("expected precondition failed, got %v", err)
+	}
+}
+
+func TestClient_UserId(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/services/oauth2/userinfo" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"user_id":"005000000000002AAA","organization_id":"00D000000000001AAA"}`))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	client.SetIdentityUrl("https://login.salesforce.com/id/00D000000000001AAA/005000000000001AAA")
+	if id, err := client.UserId(ctx); err != nil || id != "005000000000001AAA" {
+		t.Errorf("expected the user of the identity url, got %q %v", id, err)
+	}
+
+	// access tokens obtained outside of the provider come without identity url
+	client = NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	for i := 0; i < 2; i++ {
+		if id, err := client.UserId(ctx); err != nil || id != "005000000000002AAA" {
+			t.Errorf("expected the user of the userinfo endpoint, got %q %v", id, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected a single userinfo request, got %d", requests)
+	}
+}
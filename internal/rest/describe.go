@@ -0,0 +1,207 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/nimajalali/go-force/forcejson"
+)
+
+const describeCacheDirName = "terraform-provider-salesforce"
+
+// DescribeCache fetches describe results lazily and at most once per run, describes don't
+// change during a run and are shared by all resources of the provider. When a TTL is set the
+// results are also kept on disk, keyed by org ID and API version, so subsequent runs skip
+// the requests until the TTL expires
+type DescribeCache struct {
+	client *Client
+	dir    string
+	ttl    time.Duration
+
+	// orgId is looked up like a describe, mu only guards the map of sobjects so it is never held
+	// during a request
+	orgId    *describeEntry
+	global   *describeEntry
+	mu       sync.Mutex
+	sobjects map[string]*describeEntry
+}
+
+// describeEntry is locked while it is fetched so concurrent callers wait for a single request
+type describeEntry struct {
+	mu    sync.Mutex
+	value interface{}
+}
+
+// NewDescribeCache returns a cache for the org of client, results are only persisted when ttl
+// is positive, in dir which defaults to the user cache directory
+func NewDescribeCache(client *Client, dir string, ttl time.Duration) (*DescribeCache, error) {
+	c := &DescribeCache{
+		client:   client,
+		ttl:      ttl,
+		orgId:    &describeEntry{},
+		global:   &describeEntry{},
+		sobjects: map[string]*describeEntry{},
+	}
+	if ttl <= 0 {
+		return c, nil
+	}
+
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find the user cache directory, set describe_cache_dir: %v", err)
+		}
+		dir = filepath.Join(userCacheDir, describeCacheDirName, "describe")
+	} else if expanded, err := homedir.Expand(dir); err == nil {
+		dir = expanded
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create describe cache directory: %v", err)
+	}
+	c.dir = dir
+	return c, nil
+}
+
+// DescribeGlobal lists all SObjects of the org
+func (c *DescribeCache) DescribeGlobal(ctx context.Context) (*DescribeGlobalResponse, error) {
+	v, err := c.load(ctx, c.global, "global.json", func() (interface{}, error) {
+		return c.client.DescribeGlobal(ctx)
+	}, &DescribeGlobalResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*DescribeGlobalResponse), nil
+}
+
+// Describe returns the fields and urls of the named SObject
+func (c *DescribeCache) Describe(ctx context.Context, name string) (*SObjectDescription, error) {
+	c.mu.Lock()
+	entry, ok := c.sobjects[name]
+	if !ok {
+		entry = &describeEntry{}
+		c.sobjects[name] = entry
+	}
+	c.mu.Unlock()
+
+	v, err := c.load(ctx, entry, filepath.Join("sobjects", name+".json"), func() (interface{}, error) {
+		return c.client.Describe(ctx, name)
+	}, &SObjectDescription{})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*SObjectDescription), nil
+}
+
+// load returns the value of entry, reading it from the file on disk when it is fresh or else
+// fetching it, errors are not cached so a failed describe is retried on the next call
+func (c *DescribeCache) load(ctx context.Context, entry *describeEntry, file string, fetch func() (interface{}, error), decoded interface{}) (interface{}, error) {
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.value != nil {
+		return entry.value, nil
+	}
+
+	// a disk cache that can't be read or written only costs the describe request
+	path, err := c.path(ctx, file)
+	if err == nil && c.read(path, decoded) {
+		entry.value = decoded
+		return decoded, nil
+	}
+
+	v, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		_ = c.write(path, v)
+	}
+	entry.value = v
+	return v, nil
+}
+
+// path returns the file of a describe result in the disk cache, an empty path when the
+// disk cache is disabled
+func (c *DescribeCache) path(ctx context.Context, file string) (string, error) {
+	if c.dir == "" {
+		return "", nil
+	}
+	orgId, err := c.organizationId(ctx)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.dir, orgId, "v"+c.client.ApiVersion(), file), nil
+}
+
+// organizationId looks up the ID of the org once, it keys the disk cache since describes
+// differ between orgs sharing the same instance. Concurrent callers wait for a single request
+// and errors are not cached
+func (c *DescribeCache) organizationId(ctx context.Context) (string, error) {
+	c.orgId.mu.Lock()
+	defer c.orgId.mu.Unlock()
+	if c.orgId.value != nil {
+		return c.orgId.value.(string), nil
+	}
+
+	var resp struct {
+		BaseQuery
+		Records []struct {
+			Id string
+		}
+	}
+	if err := c.client.Query(ctx, BuildQuery("Id", "Organization", nil), &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Records) == 0 || resp.Records[0].Id == "" {
+		return "", fmt.Errorf("unable to find the ID of the organization")
+	}
+	c.orgId.value = resp.Records[0].Id
+	return resp.Records[0].Id, nil
+}
+
+func (c *DescribeCache) read(path string, out interface{}) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return false
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return forcejson.Unmarshal(b, out) == nil
+}
+
+func (c *DescribeCache) write(path string, v interface{}) error {
+	b, err := forcejson.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// write to a temporary file first so concurrent runs never read a partial describe
+	f, err := os.CreateTemp(filepath.Dir(path), ".describe-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
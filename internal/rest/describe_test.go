@@ -0,0 +1,199 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func describeServer(t *testing.T, requests map[string]int) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/services/data/v55.0/query":
+			_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"00D000000000001AAA"}]}`))
+		case "/services/data/v55.0/sobjects":
+			_, _ = w.Write([]byte(`{"encoding":"UTF-8","maxBatchSize":200,"sobjects":[{"name":"User","keyPrefix":"005"}]}`))
+		case "/services/data/v55.0/sobjects/User/describe":
+			_, _ = w.Write([]byte(`{"name":"User","keyPrefix":"005","urls":{"rowTemplate":"/services/data/v55.0/sobjects/User/{ID}"},"fields":[{"name":"Id","type":"id"}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDescribeCache_oncePerRun(t *testing.T) {
+	requests := map[string]int{}
+	server := describeServer(t, requests)
+	defer server.Close()
+
+	cache, err := NewDescribeCache(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			meta, err := cache.Describe(ctx, "User")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if meta.URLs["rowTemplate"] != "/services/data/v55.0/sobjects/User/{ID}" || len(meta.Fields) != 1 {
+				t.Errorf("unexpected describe %#v", meta)
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 2; i++ {
+		global, err := cache.DescribeGlobal(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if global.MaxBatchSize != 200 || len(global.SObjects) != 1 {
+			t.Errorf("unexpected global describe %#v", global)
+		}
+	}
+
+	expected := map[string]int{
+		"/services/data/v55.0/sobjects/User/describe": 1,
+		"/services/data/v55.0/sobjects":               1,
+	}
+	if len(requests) != len(expected) {
+		t.Errorf("unexpected requests %v", requests)
+	}
+	for path, count := range expected {
+		if requests[path] != count {
+			t.Errorf("expected %d requests to %s, got %d", count, path, requests[path])
+		}
+	}
+}
+
+func TestDescribeCache_disk(t *testing.T) {
+	dir := t.TempDir()
+	requests := map[string]int{}
+	server := describeServer(t, requests)
+	defer server.Close()
+	client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+	ctx := context.Background()
+
+	// every run uses a new cache, only the first one requests the describe
+	for i := 0; i < 2; i++ {
+		cache, err := NewDescribeCache(client, dir, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta, err := cache.Describe(ctx, "User")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Name != "User" || meta.URLs["rowTemplate"] != "/services/data/v55.0/sobjects/User/{ID}" || len(meta.Fields) != 1 {
+			t.Errorf("run %d: unexpected describe %#v", i, meta)
+		}
+	}
+	if requests["/services/data/v55.0/sobjects/User/describe"] != 1 {
+		t.Errorf("expected the second run to read the describe from disk, got %v", requests)
+	}
+	path := filepath.Join(dir, "00D000000000001AAA", "v55.0", "sobjects", "User.json")
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the describe to be cached in %s: %v", path, err)
+	}
+
+	// an expired entry is requested again
+	expired := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, expired, expired); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDescribeCache(client, dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Describe(ctx, "User"); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/services/data/v55.0/sobjects/User/describe"] != 2 {
+		t.Errorf("expected the expired describe to be requested again, got %v", requests)
+	}
+}
+
+func TestDescribeCache_slowOrganizationQuery(t *testing.T) {
+	var queries int32
+	queried := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v55.0/query":
+			// the first lookup fails, the second one hangs until the test releases it
+			if atomic.AddInt32(&queries, 1) == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`[{"message":"sObject type 'Organization' is not supported.","errorCode":"INVALID_TYPE"}]`))
+				return
+			}
+			close(queried)
+			<-release
+			_, _ = w.Write([]byte(`{"totalSize":1,"done":true,"records":[{"Id":"00D000000000001AAA"}]}`))
+		case "/services/data/v55.0/sobjects":
+			_, _ = w.Write([]byte(`{"encoding":"UTF-8","maxBatchSize":200,"sobjects":[{"name":"User","keyPrefix":"005"}]}`))
+		case "/services/data/v55.0/sobjects/User/describe":
+			_, _ = w.Write([]byte(`{"name":"User","keyPrefix":"005","urls":{"rowTemplate":"/services/data/v55.0/sobjects/User/{ID}"},"fields":[{"name":"Id","type":"id"}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	cache, err := NewDescribeCache(NewClient(server.Client(), server.URL, "55.0", RetryPolicy{}), t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// without the ID of the org the describe is only kept in memory
+	if _, err := cache.Describe(ctx, "User"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := cache.DescribeGlobal(ctx)
+		done <- err
+	}()
+	<-queried
+
+	// the describe in memory is returned while the ID of the org is being looked up
+	described := make(chan error)
+	go func() {
+		_, err := cache.Describe(ctx, "User")
+		described <- err
+	}()
+	select {
+	case err := <-described:
+		if err != nil {
+			t.Error(err)
+		}
+		close(release)
+	case <-time.After(5 * time.Second):
+		t.Error("expected the describe not to wait for the organization query")
+		close(release)
+		<-described
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
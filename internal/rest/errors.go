@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error is a single error reported by the REST API
+type Error struct {
+	ErrorCode string   `force:"errorCode,omitempty"`
+	Message   string   `force:"message,omitempty"`
+	Fields    []string `force:"fields,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) > 0 {
+		return fmt.Sprintf("%s: %s (fields: %s)", e.ErrorCode, e.Message, strings.Join(e.Fields, ", "))
+	}
+	return fmt.Sprintf("%s: %s", e.ErrorCode, e.Message)
+}
+
+// impliedFields lists the field of error codes that don't report one in fields
+var impliedFields = map[string][]string{
+	"DUPLICATE_USERNAME":       {"Username"},
+	"DUPLICATE_DEVELOPER_NAME": {"DeveloperName"},
+	"DUPLICATE_COMM_NICKNAME":  {"CommunityNickname"},
+	"INVALID_EMAIL_ADDRESS":    {"Email"},
+	"LICENSE_LIMIT_EXCEEDED":   {"ProfileId"},
+}
+
+// FieldNames returns the API names of the fields the error relates to
+func (e *Error) FieldNames() []string {
+	if len(e.Fields) > 0 {
+		return e.Fields
+	}
+	return impliedFields[e.ErrorCode]
+}
+
+// Errors is returned when the API rejects a request, StatusCode is the HTTP status of the response
+type Errors struct {
+	StatusCode int
+	Errors     []*Error
+}
+
+func (e *Errors) Error() string {
+	s := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// HasCode returns true if any of the errors has the given error code
+func (e *Errors) HasCode(code string) bool {
+	for _, err := range e.Errors {
+		if err.ErrorCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPreconditionFailed returns true if a conditional request was rejected because the record
+// was modified since the given time
+func IsPreconditionFailed(err error) bool {
+	var apiErrors *Errors
+	return errors.As(err, &apiErrors) && apiErrors.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsNotFound returns true if the error reports that the record doesn't exist or was deleted
+func IsNotFound(err error) bool {
+	var apiErrors *Errors
+	if !errors.As(err, &apiErrors) {
+		return false
+	}
+	return apiErrors.StatusCode == http.StatusNotFound || apiErrors.HasCode("NOT_FOUND") || apiErrors.HasCode("ENTITY_IS_DELETED")
+}
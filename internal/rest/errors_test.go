@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResponseError(t *testing.T) {
+	cases := map[string]struct {
+		status   int
+		body     string
+		code     string
+		fields   []string
+		notFound bool
+	}{
+		"duplicate username": {
+			status: http.StatusBadRequest,
+			body:   `[{"message":"Duplicate Username.","errorCode":"DUPLICATE_USERNAME","fields":[]}]`,
+			code:   "DUPLICATE_USERNAME",
+			fields: []string{"Username"},
+		},
+		"required field": {
+			status: http.StatusBadRequest,
+			body:   `[{"message":"Required fields are missing: [LastName]","errorCode":"REQUIRED_FIELD_MISSING","fields":["LastName"]}]`,
+			code:   "REQUIRED_FIELD_MISSING",
+			fields: []string{"LastName"},
+		},
+		"not found": {
+			status:   http.StatusNotFound,
+			body:     `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`,
+			code:     "NOT_FOUND",
+			notFound: true,
+		},
+		"deleted": {
+			status:   http.StatusBadRequest,
+			body:     `[{"errorCode":"ENTITY_IS_DELETED","message":"entity is deleted"}]`,
+			code:     "ENTITY_IS_DELETED",
+			notFound: true,
+		},
+		"not json": {
+			status:   http.StatusNotFound,
+			body:     `<html>Not Found</html>`,
+			code:     "NOT_FOUND",
+			notFound: true,
+		},
+		"invalid type": {
+			status: http.StatusBadRequest,
+			body:   `[{"errorCode":"INVALID_TYPE","message":"sObject type 'Foo' does not exist"}]`,
+			code:   "INVALID_TYPE",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(c.status)
+				_, _ = fmt.Fprint(w, c.body)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.Client(), server.URL, "55.0", RetryPolicy{})
+			err := client.Get(context.Background(), "005000000000001AAA", nil, &testUser{})
+			var apiErrors *Errors
+			if !errors.As(err, &apiErrors) {
+				t.Fatalf("expected *Errors, got %#v", err)
+			}
+			if apiErrors.StatusCode != c.status || len(apiErrors.Errors) != 1 || apiErrors.Errors[0].ErrorCode != c.code {
+				t.Errorf("unexpected error %#v", apiErrors.Errors[0])
+			}
+			if fields := apiErrors.Errors[0].FieldNames(); !reflect.DeepEqual(fields, c.fields) {
+				t.Errorf("expected fields %v, got %v", c.fields, fields)
+			}
+			if IsNotFound(err) != c.notFound {
+				t.Errorf("expected IsNotFound to be %t", c.notFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// BaseQuery holds the fields common to all query responses, embed it next to a Records field
+type BaseQuery struct {
+	Done           bool   `force:"done"`
+	TotalSize      int    `force:"totalSize"`
+	NextRecordsUri string `force:"nextRecordsUrl"`
+}
+
+// BuildQuery builds a SOQL query selecting fields from table, the constraints are joined with AND
+func BuildQuery(fields, table string, constraints []string) string {
+	query := fmt.Sprintf("SELECT %s FROM %s", fields, table)
+	if len(constraints) > 0 {
+		query += " WHERE " + strings.Join(constraints, " AND ")
+	}
+	return query
+}
+
+// QuoteString escapes a value for use as a string literal in a SOQL query
+func QuoteString(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// Query runs a SOQL query and decodes the first batch of results into out
+func (c *Client) Query(ctx context.Context, query string, out interface{}) error {
+	return c.Do(ctx, http.MethodGet, c.dataPath("query"), url.Values{"q": {query}}, nil, out)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultMaxRetries   = 5
+	DefaultMaxRetryWait = 30 * time.Second
+)
+
+// retryBaseDelay is the wait before the first retry, it doubles with every attempt
+var retryBaseDelay = time.Second
+
+// retryableErrorCodes are the error codes of requests that were rejected before any change
+// was made, so they are safe to send again even if they aren't idempotent
+var retryableErrorCodes = map[string]bool{
+	"UNABLE_TO_LOCK_ROW":     true,
+	"REQUEST_LIMIT_EXCEEDED": true,
+	"SERVER_UNAVAILABLE":     true,
+}
+
+// idempotentMethods are safe to send again after a failure that doesn't say whether the request
+// was processed, such as a 503 from a proxy
+var idempotentMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodHead:   true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// RetryPolicy controls how requests failing with a transient error are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, 0 disables retries
+	MaxRetries int
+	// MaxRetryWait caps the wait between two attempts, a Retry-After longer than that isn't retried
+	MaxRetryWait time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when the provider settings are unset
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:   DefaultMaxRetries,
+		MaxRetryWait: DefaultMaxRetryWait,
+	}
+}
+
+// wait returns how long to wait before retrying a failed attempt, and whether it should be retried at all
+func (p RetryPolicy) wait(attempt int, method string, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !isRetryable(method, resp, err) {
+		return 0, false
+	}
+	maxWait := p.MaxRetryWait
+	if maxWait <= 0 {
+		maxWait = DefaultMaxRetryWait
+	}
+
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return retryAfter, retryAfter <= maxWait
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff is the exponential backoff with full jitter over the upper half of the interval
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	maxWait := p.MaxRetryWait
+	if maxWait <= 0 {
+		maxWait = DefaultMaxRetryWait
+	}
+	backoff := retryBaseDelay << attempt
+	if backoff <= 0 || backoff > maxWait {
+		backoff = maxWait
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+func isRetryable(method string, resp *http.Response, err error) bool {
+	var apiErrors *Errors
+	if errors.As(err, &apiErrors) && retryableErrors(apiErrors.Errors, resp.Header.Get("Retry-After") != "") {
+		return true
+	}
+	return resp.StatusCode == http.StatusServiceUnavailable && idempotentMethods[method]
+}
+
+// retryableErrors reports whether every error is retryable. REQUEST_LIMIT_EXCEEDED is mostly the
+// daily limit of the org which a backoff of seconds won't outlast, it's only retried when the
+// server tells when to
+func retryableErrors(apiErrors []*Error, retryAfter bool) bool {
+	for _, apiErr := range apiErrors {
+		if !retryableErrorCodes[apiErr.ErrorCode] || (apiErr.ErrorCode == "REQUEST_LIMIT_EXCEEDED" && !retryAfter) {
+			return false
+		}
+	}
+	return len(apiErrors) > 0
+}
+
+// parseRetryAfter supports both the delay in seconds and the HTTP date formats of Retry-After
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
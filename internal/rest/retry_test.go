@@ -0,0 +1,137 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_retry(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	type response struct {
+		status     int
+		body       string
+		retryAfter string
+	}
+	lockRow := response{http.StatusBadRequest, `[{"errorCode":"UNABLE_TO_LOCK_ROW","message":"unable to obtain exclusive access to this record"}]`, ""}
+	created := response{http.StatusCreated, `{"id":"005000000000001AAA","success":true,"errors":[]}`, ""}
+	found := response{http.StatusOK, `{"Id":"005000000000001AAA","LastName":"Doe"}`, ""}
+	serverUnavailable := response{http.StatusServiceUnavailable, `[{"errorCode":"SERVER_UNAVAILABLE","message":"Server unavailable."}]`, ""}
+	requestLimit := `[{"errorCode":"REQUEST_LIMIT_EXCEEDED","message":"TotalRequests Limit exceeded."}]`
+
+	cases := map[string]struct {
+		responses []response
+		policy    RetryPolicy
+		get       bool
+		attempts  int
+		err       bool
+	}{
+		"lock row": {
+			responses: []response{lockRow, lockRow, created},
+			policy:    RetryPolicy{MaxRetries: 3},
+			attempts:  3,
+		},
+		"service unavailable": {
+			responses: []response{serverUnavailable, created},
+			policy:    RetryPolicy{MaxRetries: 3},
+			attempts:  2,
+		},
+		"bare service unavailable": {
+			responses: []response{{http.StatusServiceUnavailable, "unavailable", "0"}},
+			policy:    RetryPolicy{MaxRetries: 3},
+			attempts:  1,
+			err:       true,
+		},
+		"bare service unavailable on get": {
+			responses: []response{{http.StatusServiceUnavailable, "unavailable", "0"}, found},
+			policy:    RetryPolicy{MaxRetries: 3},
+			get:       true,
+			attempts:  2,
+		},
+		"request limit": {
+			responses: []response{{http.StatusForbidden, requestLimit, ""}},
+			policy:    RetryPolicy{MaxRetries: 3},
+			attempts:  1,
+			err:       true,
+		},
+		"request limit with retry after": {
+			responses: []response{{http.StatusForbidden, requestLimit, "0"}, created},
+			policy:    RetryPolicy{MaxRetries: 1},
+			attempts:  2,
+		},
+		"retries exhausted": {
+			responses: []response{lockRow, lockRow, lockRow},
+			policy:    RetryPolicy{MaxRetries: 2},
+			attempts:  3,
+			err:       true,
+		},
+		"retries disabled": {
+			responses: []response{lockRow},
+			attempts:  1,
+			err:       true,
+		},
+		"not retryable": {
+			responses: []response{{http.StatusBadRequest, `[{"errorCode":"DUPLICATE_USERNAME","message":"Duplicate Username.","fields":["Username"]}]`, ""}},
+			policy:    RetryPolicy{MaxRetries: 3},
+			attempts:  1,
+			err:       true,
+		},
+		"retry after too long": {
+			responses: []response{{http.StatusServiceUnavailable, serverUnavailable.body, "120"}},
+			policy:    RetryPolicy{MaxRetries: 3, MaxRetryWait: time.Minute},
+			attempts:  1,
+			err:       true,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				resp := c.responses[attempts]
+				attempts++
+				if resp.retryAfter != "" {
+					w.Header().Set("Retry-After", resp.retryAfter)
+				}
+				w.WriteHeader(resp.status)
+				_, _ = w.Write([]byte(resp.body))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.Client(), server.URL, "55.0", c.policy)
+			var err error
+			if c.get {
+				err = client.Get(context.Background(), "005000000000001AAA", nil, &testUser{})
+			} else {
+				_, err = client.Insert(context.Background(), testUser{LastName: "Doe"})
+			}
+			if c.err && err == nil {
+				t.Error("expected error")
+			}
+			if !c.err && err != nil {
+				t.Error(err)
+			}
+			if attempts != c.attempts {
+				t.Errorf("expected %d attempts, got %d", c.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if wait, ok := parseRetryAfter("3"); !ok || wait != 3*time.Second {
+		t.Errorf("expected 3s, got %s", wait)
+	}
+	if wait, ok := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); !ok || wait <= 50*time.Second || wait > time.Minute {
+		t.Errorf("expected about a minute, got %s", wait)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+}
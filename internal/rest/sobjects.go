@@ -0,0 +1,123 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SObject is implemented by the records sent to and read from the API, fields are
+// mapped with the force struct tag
+type SObject interface {
+	ApiName() string
+	ExternalIdApiName() string
+}
+
+// SObjectResponse is returned when inserting a record
+type SObjectResponse struct {
+	Id      string   `force:"id,omitempty"`
+	Errors  []*Error `force:"errors,omitempty"`
+	Success bool     `force:"success,omitempty"`
+}
+
+// Get reads the record with the given id into out, all fields are returned when fields is empty
+func (c *Client) Get(ctx context.Context, id string, fields []string, out SObject) error {
+	var params url.Values
+	if len(fields) > 0 {
+		params = url.Values{"fields": {strings.Join(fields, ",")}}
+	}
+	return c.Do(ctx, http.MethodGet, c.dataPath("sobjects", out.ApiName(), id), params, nil, out)
+}
+
+// Insert creates a record and returns its id
+func (c *Client) Insert(ctx context.Context, in SObject) (string, error) {
+	var resp SObjectResponse
+	if err := c.Do(ctx, http.MethodPost, c.dataPath("sobjects", in.ApiName()), nil, in, &resp); err != nil {
+		return "", err
+	}
+	if !resp.Success && len(resp.Errors) > 0 {
+		return "", &Errors{StatusCode: http.StatusBadRequest, Errors: resp.Errors}
+	}
+	if resp.Id == "" {
+		return "", fmt.Errorf("Salesforce did not return the id of the inserted %s", in.ApiName())
+	}
+	return resp.Id, nil
+}
+
+// Update patches the record with the given id with the fields of in
+func (c *Client) Update(ctx context.Context, id string, in SObject) error {
+	return c.Do(ctx, http.MethodPatch, c.dataPath("sobjects", in.ApiName(), id), nil, in, nil)
+}
+
+// UpdateIfUnmodifiedSince patches the record like Update, unless it was modified after since in
+// which case Salesforce rejects the request with a 412 status, see IsPreconditionFailed
+func (c *Client) UpdateIfUnmodifiedSince(ctx context.Context, id string, in SObject, since time.Time) error {
+	header := http.Header{"If-Unmodified-Since": {since.UTC().Format(http.TimeFormat)}}
+	return c.do(ctx, http.MethodPatch, c.dataPath("sobjects", in.ApiName(), id), nil, header, in, nil)
+}
+
+// Delete deletes the record with the given id, sobject is only used for its ApiName
+func (c *Client) Delete(ctx context.Context, id string, sobject SObject) error {
+	return c.Do(ctx, http.MethodDelete, c.dataPath("sobjects", sobject.ApiName(), id), nil, nil, nil)
+}
+
+// DescribeGlobalResponse lists the SObjects available in the org
+type DescribeGlobalResponse struct {
+	Encoding     string                  `force:"encoding"`
+	MaxBatchSize int                     `force:"maxBatchSize"`
+	SObjects     []SObjectDescribeResult `force:"sobjects"`
+}
+
+// SObjectDescribeResult is the summary of an SObject included in the global describe
+type SObjectDescribeResult struct {
+	Name       string            `force:"name"`
+	Label      string            `force:"label"`
+	KeyPrefix  string            `force:"keyPrefix"`
+	Createable bool              `force:"createable"`
+	Updateable bool              `force:"updateable"`
+	Deletable  bool              `force:"deletable"`
+	Queryable  bool              `force:"queryable"`
+	URLs       map[string]string `force:"urls"`
+}
+
+// SObjectDescription is the full describe of an SObject
+type SObjectDescription struct {
+	SObjectDescribeResult
+	Fields []FieldDescription `force:"fields"`
+}
+
+// FieldDescription describes a field of an SObject
+type FieldDescription struct {
+	Name        string   `force:"name"`
+	Label       string   `force:"label"`
+	Type        string   `force:"type"`
+	Length      int      `force:"length"`
+	Nillable    bool     `force:"nillable"`
+	Createable  bool     `force:"createable"`
+	Updateable  bool     `force:"updateable"`
+	ReferenceTo []string `force:"referenceTo"`
+}
+
+// DescribeGlobal lists all SObjects of the org
+func (c *Client) DescribeGlobal(ctx context.Context) (*DescribeGlobalResponse, error) {
+	var resp DescribeGlobalResponse
+	if err := c.Do(ctx, http.MethodGet, c.dataPath("sobjects"), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Describe returns the fields and urls of the named SObject
+func (c *Client) Describe(ctx context.Context, name string) (*SObjectDescription, error) {
+	var resp SObjectDescription
+	if err := c.Do(ctx, http.MethodGet, c.dataPath("sobjects", name, "describe"), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
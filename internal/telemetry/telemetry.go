@@ -0,0 +1,114 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package telemetry traces provider operations with OpenTelemetry. Tracing is off unless an
+// OTLP endpoint is configured with the standard OTEL_* environment variables
+package telemetry
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "terraform-provider-salesforce"
+	tracerName  = "github.com/hashicorp/terraform-provider-salesforce"
+)
+
+const (
+	// SObjectTypeKey is the API name of the SObject an operation or request applies to
+	SObjectTypeKey = attribute.Key("salesforce.sobject.type")
+	// RecordIdKey is the ID of the record an operation or request applies to
+	RecordIdKey = attribute.Key("salesforce.record.id")
+	// BatchSizeKey is the number of records of a batch sent on behalf of several operations
+	BatchSizeKey = attribute.Key("salesforce.batch.size")
+)
+
+// enabled follows the OpenTelemetry SDK configuration: tracing requires an OTLP endpoint and
+// can be turned off with OTEL_SDK_DISABLED or OTEL_TRACES_EXPORTER=none
+func enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	if exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter != "" && exporter != "otlp" {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func protocol() string {
+	if p := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"); p != "" {
+		return p
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+}
+
+// Setup installs the global tracer provider when tracing is enabled, the returned function
+// flushes the pending spans and must be called before the process exits. It is never nil, even
+// when an error is returned
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if !enabled() {
+		return noop, nil
+	}
+
+	// the exporters read the endpoint, headers, timeout and TLS settings from the environment
+	var client otlptrace.Client
+	if protocol() == "grpc" {
+		client = otlptracegrpc.NewClient()
+	} else {
+		client = otlptracehttp.NewClient()
+	}
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return noop, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence over the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span with the global tracer provider, a no-op span when tracing is disabled
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartLinkedSpan starts a root span linked to the spans of the operations it serves, such as a
+// batch gathering the records of several operations
+func StartLinkedSpan(ctx context.Context, name string, links []trace.Link, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithNewRoot(), trace.WithLinks(links...), trace.WithAttributes(attributes...))
+}
+
+// SObjectAttributes tags a span with the SObject type and record ID it applies to, the ID is
+// omitted when it isn't known yet
+func SObjectAttributes(sobjectType string, id string) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{SObjectTypeKey.String(sobjectType)}
+	if id != "" {
+		attributes = append(attributes, RecordIdKey.String(id))
+	}
+	return attributes
+}
@@ -5,9 +5,12 @@ package main
 
 import (
 	"context"
+	"log"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-provider-salesforce/internal/provider"
+	"github.com/hashicorp/terraform-provider-salesforce/internal/telemetry"
 )
 
 // Run "go generate" to format example terraform files and generate the docs for the registry/website
@@ -29,8 +32,34 @@ var (
 	// commit  string = ""
 )
 
+// shutdownTimeout bounds the flush of the pending spans, within the time Terraform waits for the
+// provider to exit
+const shutdownTimeout = time.Second
+
 func main() {
-	providerserver.Serve(context.Background(), provider.New, providerserver.ServeOpts{
+	ctx := context.Background()
+
+	// tracing is only enabled when an OTLP endpoint is set through the OTEL_* environment variables.
+	// tflog needs the logger Terraform sets up for each RPC, outside of them the standard logger
+	// writes to the output Terraform collects from the provider
+	shutdown, err := telemetry.Setup(ctx, version)
+	if err != nil {
+		log.Printf("[WARN] OpenTelemetry tracing disabled: %s", err)
+	}
+
+	err = providerserver.Serve(ctx, provider.New, providerserver.ServeOpts{
 		Address: "registry.terraform.io/hashicorp/salesforce",
 	})
+
+	// the pending spans are flushed once Terraform stops the provider. This is best effort, Terraform
+	// kills providers that don't exit within a couple of seconds and the spans still queued are lost
+	flushCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	if flushErr := shutdown(flushCtx); flushErr != nil {
+		log.Printf("[WARN] Flushing OpenTelemetry spans: %s", flushErr)
+	}
+	cancel()
+
+	if err != nil {
+		log.Fatal(err.Error())
+	}
 }